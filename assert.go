@@ -0,0 +1,606 @@
+package main
+
+import (
+	"fmt"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assertion is a check on the records published by a test, written in the
+// assertion language of suite files:
+//
+//	record where email == "a@b.com" exists
+//	record where email == "a@b.com" has first_name == "Ann" and gender == "Female"
+//	record where email == "a@b.com" has all fields equal to [1, "Ann", "Lee", "a@b.com", "Female", null]
+//	record where id == 52 is invalid
+//	no record has null in column id
+//	column magnitude is always within [0, 100]
+//	exactly 3 records match extinct == true
+//	exactly 1 record is invalid
+//
+// Columns are property names of the published schema, quoted if they contain
+// spaces. Values are JSON literals; strings which are dates match the same
+// instant in any RFC 3339 form. Each assertion sees every record once, as it's
+// published, so any number of them are checked in a single pass over the stream.
+type assertion interface {
+	// evaluate checks a published record, whose data is in the order of the published schema.
+	evaluate(record *plugin.PublishRecord, data []interface{})
+	// result returns an error if the assertion doesn't hold for the records evaluated.
+	result() error
+	String() string
+}
+
+// parseAssertion parses text as an assertion about records published with schema.
+func parseAssertion(text string, schema plugin.Schema) (assertion, error) {
+	tokens, err := tokenizeAssertion(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &assertionParser{text: text, tokens: tokens, schema: schema}
+
+	var a assertion
+	switch {
+	case p.accept("record"):
+		a, err = p.parseRecord()
+	case p.accept("no"):
+		a, err = p.parseNoNull()
+	case p.accept("column"):
+		a, err = p.parseRange()
+	case p.accept("exactly"):
+		a, err = p.parseCount()
+	default:
+		err = p.expected(`"record", "no", "column" or "exactly"`)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.expected("the end of the assertion")
+	}
+	return a, nil
+}
+
+// term is a column compared with a value.
+type term struct {
+	index int
+	name  string
+	value interface{}
+}
+
+func (t term) matches(data []interface{}) bool {
+	return t.index < len(data) && valuesEqual(t.value, data[t.index])
+}
+
+// condition is a conjunction of terms.
+type condition []term
+
+func (c condition) matches(data []interface{}) bool {
+	for _, t := range c {
+		if !t.matches(data) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares a value from an assertion with a value from published data.
+func valuesEqual(want, got interface{}) bool {
+	switch w := want.(type) {
+	case float64:
+		g, ok := got.(float64)
+		return ok && math.Abs(g-w) <= 0.00001
+	case string:
+		g, ok := got.(string)
+		if !ok {
+			return false
+		}
+		if g == w {
+			return true
+		}
+		wantTime, err := time.Parse(time.RFC3339Nano, w)
+		if err != nil {
+			return false
+		}
+		gotTime, err := time.Parse(time.RFC3339Nano, g)
+		return err == nil && wantTime.Equal(gotTime)
+	default:
+		return want == got
+	}
+}
+
+// assertionFailureLimit is the number of offending records an assertion describes.
+const assertionFailureLimit = 3
+
+// failures collects descriptions of the records which broke an assertion.
+type failures struct {
+	count   int
+	details []string
+}
+
+func (f *failures) add(format string, args ...interface{}) {
+	f.count++
+	if len(f.details) < assertionFailureLimit {
+		f.details = append(f.details, fmt.Sprintf(format, args...))
+	}
+}
+
+func (f *failures) err(what string) error {
+	if f.count == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("%d %s: %s", f.count, what, strings.Join(f.details, "; "))
+	if f.count > len(f.details) {
+		msg += "; ..."
+	}
+	return errors.New(msg)
+}
+
+// recordAssertion checks the records matching a condition.
+type recordAssertion struct {
+	text  string
+	where condition
+	// fields must match every matching record, if all is nil.
+	fields condition
+	// all are the values of every field of every matching record, if set.
+	all []interface{}
+	// invalid is whether matching records must be invalid, if set.
+	invalid *bool
+
+	matched  int
+	failures failures
+}
+
+func (a *recordAssertion) evaluate(record *plugin.PublishRecord, data []interface{}) {
+	if !a.where.matches(data) {
+		return
+	}
+	a.matched++
+
+	switch {
+	case a.invalid != nil:
+		if record.Invalid != *a.invalid {
+			a.failures.add("{ %s }", record)
+		}
+	case a.all != nil:
+		if len(data) != len(a.all) {
+			a.failures.add("%s has %d fields, not %d", record.Data, len(data), len(a.all))
+			return
+		}
+		for i, want := range a.all {
+			if !valuesEqual(want, data[i]) {
+				a.failures.add("%s has %v at index %d, not %v", record.Data, data[i], i, want)
+				return
+			}
+		}
+	default:
+		for _, t := range a.fields {
+			if !t.matches(data) {
+				a.failures.add("%s has %s == %v", record.Data, t.name, valueAt(data, t.index))
+				return
+			}
+		}
+	}
+}
+
+func (a *recordAssertion) result() error {
+	if a.matched == 0 {
+		return errors.New("no matching record was published")
+	}
+	return a.failures.err(fmt.Sprintf("of %d matching records didn't", a.matched))
+}
+
+func (a *recordAssertion) String() string {
+	return a.text
+}
+
+// noNullAssertion checks that a column is never null.
+type noNullAssertion struct {
+	text     string
+	index    int
+	failures failures
+}
+
+func (a *noNullAssertion) evaluate(record *plugin.PublishRecord, data []interface{}) {
+	if valueAt(data, a.index) == nil {
+		a.failures.add("%s", record.Data)
+	}
+}
+
+func (a *noNullAssertion) result() error {
+	return a.failures.err("records had null")
+}
+
+func (a *noNullAssertion) String() string {
+	return a.text
+}
+
+// rangeAssertion checks that the values in a column are numbers within a range.
+// Nulls are allowed; assert there are no nulls separately.
+type rangeAssertion struct {
+	text     string
+	index    int
+	min, max float64
+	failures failures
+}
+
+func (a *rangeAssertion) evaluate(record *plugin.PublishRecord, data []interface{}) {
+	switch v := valueAt(data, a.index).(type) {
+	case nil:
+	case float64:
+		if v < a.min || v > a.max {
+			a.failures.add("%v", v)
+		}
+	default:
+		a.failures.add("%v (%T)", v, v)
+	}
+}
+
+func (a *rangeAssertion) result() error {
+	return a.failures.err("values were out of range")
+}
+
+func (a *rangeAssertion) String() string {
+	return a.text
+}
+
+// countAssertion checks the number of records which match a condition, or are invalid.
+type countAssertion struct {
+	text  string
+	want  int
+	where condition
+	// invalid counts invalid records instead of records matching where.
+	invalid bool
+	count   int
+}
+
+func (a *countAssertion) evaluate(record *plugin.PublishRecord, data []interface{}) {
+	if a.invalid && record.Invalid || !a.invalid && a.where.matches(data) {
+		a.count++
+	}
+}
+
+func (a *countAssertion) result() error {
+	if a.count != a.want {
+		return errors.Errorf("%d records matched", a.count)
+	}
+	return nil
+}
+
+func (a *countAssertion) String() string {
+	return a.text
+}
+
+func valueAt(data []interface{}, i int) interface{} {
+	if i < len(data) {
+		return data[i]
+	}
+	return nil
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	stringToken
+	numberToken
+	punctToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// col is the (1-based) column the token starts at, for error messages.
+	col   int
+	value interface{}
+}
+
+// tokenizeAssertion splits text into words, quoted strings, numbers and punctuation.
+func tokenizeAssertion(text string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '[' || c == ']' || c == ',':
+			tokens = append(tokens, token{kind: punctToken, text: text[i : i+1], col: i + 1})
+			i++
+		case strings.HasPrefix(text[i:], "=="):
+			tokens = append(tokens, token{kind: punctToken, text: "==", col: i + 1})
+			i += 2
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, errors.Errorf("unterminated string at column %d", i+1)
+			}
+			s, err := strconv.Unquote(text[i : end+1])
+			if err != nil {
+				return nil, errors.Errorf("bad string %s at column %d", text[i:end+1], i+1)
+			}
+			tokens = append(tokens, token{kind: stringToken, text: text[i : end+1], col: i + 1, value: s})
+			i = end + 1
+		case c == '-' || c == '.' || c >= '0' && c <= '9':
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			n, err := strconv.ParseFloat(text[i:end], 64)
+			if err != nil {
+				return nil, errors.Errorf("bad number %s at column %d", text[i:end], i+1)
+			}
+			tokens = append(tokens, token{kind: numberToken, text: text[i:end], col: i + 1, value: n})
+			i = end
+		default:
+			end := i
+			for end < len(text) && strings.IndexByte(" \t[],=\"", text[end]) < 0 {
+				end++
+			}
+			if end == i {
+				return nil, errors.Errorf("unexpected %q at column %d", text[i:i+1], i+1)
+			}
+			tokens = append(tokens, token{kind: wordToken, text: text[i:end], col: i + 1})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type assertionParser struct {
+	text   string
+	tokens []token
+	pos    int
+	schema plugin.Schema
+}
+
+func (p *assertionParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// accept consumes the next token if it's one of words.
+func (p *assertionParser) accept(words ...string) bool {
+	t := p.peek()
+	if t == nil || t.kind == stringToken {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *assertionParser) expect(words ...string) error {
+	if p.accept(words...) {
+		return nil
+	}
+	return p.expected(strings.Join(quoteAll(words), " or "))
+}
+
+func (p *assertionParser) expected(what string) error {
+	if t := p.peek(); t != nil {
+		return errors.Errorf("expected %s at column %d, got %s", what, t.col, t.text)
+	}
+	return errors.Errorf("expected %s at column %d, got the end", what, len(p.text)+1)
+}
+
+// column parses a property name of the published schema and returns its index.
+func (p *assertionParser) column() (int, string, error) {
+	t := p.peek()
+	if t == nil || t.kind != wordToken && t.kind != stringToken {
+		return 0, "", p.expected("a column")
+	}
+	name := t.text
+	if t.kind == stringToken {
+		name = t.value.(string)
+	}
+	for i, prop := range p.schema.Properties {
+		if prop.Name == name {
+			p.pos++
+			return i, name, nil
+		}
+	}
+	return 0, "", errors.Errorf("schema %s has no column %q (at column %d)", p.schema.Name, name, t.col)
+}
+
+func (p *assertionParser) value() (interface{}, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.expected("a value")
+	}
+	switch {
+	case t.kind == stringToken || t.kind == numberToken:
+		p.pos++
+		return t.value, nil
+	case p.accept("true"):
+		return true, nil
+	case p.accept("false"):
+		return false, nil
+	case p.accept("null"):
+		return nil, nil
+	}
+	return nil, p.expected("a value")
+}
+
+func (p *assertionParser) number() (float64, error) {
+	t := p.peek()
+	if t == nil || t.kind != numberToken {
+		return 0, p.expected("a number")
+	}
+	p.pos++
+	return t.value.(float64), nil
+}
+
+func (p *assertionParser) condition() (condition, error) {
+	var c condition
+	for {
+		index, name, err := p.column()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect("=="); err != nil {
+			return nil, err
+		}
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		c = append(c, term{index: index, name: name, value: value})
+		if !p.accept("and") {
+			return c, nil
+		}
+	}
+}
+
+func (p *assertionParser) list() ([]interface{}, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	values := []interface{}{}
+	if p.accept("]") {
+		return values, nil
+	}
+	for {
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+		if p.accept("]") {
+			return values, nil
+		}
+		if err = p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseRecord parses the rest of "record where ...".
+func (p *assertionParser) parseRecord() (assertion, error) {
+	if err := p.expect("where"); err != nil {
+		return nil, err
+	}
+	where, err := p.condition()
+	if err != nil {
+		return nil, err
+	}
+	a := &recordAssertion{text: p.text, where: where}
+
+	switch {
+	case p.accept("exists"):
+	case p.accept("is"):
+		invalid := true
+		if !p.accept("invalid") {
+			if err = p.expect("valid"); err != nil {
+				return nil, err
+			}
+			invalid = false
+		}
+		a.invalid = &invalid
+	case p.accept("has"):
+		if p.accept("all") {
+			for _, w := range []string{"fields", "equal", "to"} {
+				if err = p.expect(w); err != nil {
+					return nil, err
+				}
+			}
+			if a.all, err = p.list(); err != nil {
+				return nil, err
+			}
+		} else if a.fields, err = p.condition(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.expected(`"exists", "is" or "has"`)
+	}
+	return a, nil
+}
+
+// parseNoNull parses the rest of "no record has null in column ...".
+func (p *assertionParser) parseNoNull() (assertion, error) {
+	for _, w := range []string{"record", "has", "null", "in"} {
+		if err := p.expect(w); err != nil {
+			return nil, err
+		}
+	}
+	p.accept("column")
+	index, _, err := p.column()
+	if err != nil {
+		return nil, err
+	}
+	return &noNullAssertion{text: p.text, index: index}, nil
+}
+
+// parseRange parses the rest of "column ... is always within [min, max]".
+func (p *assertionParser) parseRange() (assertion, error) {
+	index, _, err := p.column()
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range []string{"is", "always", "within", "["} {
+		if err = p.expect(w); err != nil {
+			return nil, err
+		}
+	}
+	a := &rangeAssertion{text: p.text, index: index}
+	if a.min, err = p.number(); err != nil {
+		return nil, err
+	}
+	if err = p.expect(","); err != nil {
+		return nil, err
+	}
+	if a.max, err = p.number(); err != nil {
+		return nil, err
+	}
+	if err = p.expect("]"); err != nil {
+		return nil, err
+	}
+	if a.min > a.max {
+		return nil, errors.Errorf("the range [%v, %v] is empty", a.min, a.max)
+	}
+	return a, nil
+}
+
+// parseCount parses the rest of "exactly N records match ..." or "exactly N records are invalid".
+func (p *assertionParser) parseCount() (assertion, error) {
+	n, err := p.number()
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n != math.Trunc(n) {
+		return nil, errors.Errorf("%v isn't a number of records", n)
+	}
+	if err = p.expect("record", "records"); err != nil {
+		return nil, err
+	}
+	a := &countAssertion{text: p.text, want: int(n)}
+
+	switch {
+	case p.accept("match", "matches"):
+		if a.where, err = p.condition(); err != nil {
+			return nil, err
+		}
+	case p.accept("are", "is"):
+		if err = p.expect("invalid"); err != nil {
+			return nil, err
+		}
+		a.invalid = true
+	default:
+		return nil, p.expected(`"match" or "are invalid"`)
+	}
+	return a, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"strings"
+	"testing"
+)
+
+var assertSchema = plugin.Schema{
+	Name: "animals",
+	Properties: []*plugin.Property{
+		{Name: "id", Type: "integer"},
+		{Name: "name", Type: "string"},
+		{Name: "extinct", Type: "boolean"},
+		{Name: "last spotted", Type: "datetime"},
+	},
+}
+
+// assertRecords are published records in the order of assertSchema.
+var assertRecords = []*plugin.PublishRecord{
+	{Data: `[1, "Lamprotornis nitens", false, "1963-04-29T00:00:00Z"]`},
+	{Data: `[2, "Papilio canadensis", true, "1855-02-26T00:00:00.000Z"]`},
+	{Data: `[3, "52", true, null]`},
+	{Data: `[4, "Macropus fuliginosus", null, "1806-11-25T00:00:00Z"]`, Invalid: true, Error: "extinct: \"blue\" is not a valid boolean"},
+}
+
+func evaluateAssertion(t *testing.T, text string) error {
+	a, err := parseAssertion(text, assertSchema)
+	if err != nil {
+		t.Fatalf("parseAssertion(%q): %s", text, err)
+	}
+	for _, record := range assertRecords {
+		var data []interface{}
+		if err := json.Unmarshal([]byte(record.Data), &data); err != nil {
+			t.Fatalf("bad test record %s: %s", record.Data, err)
+		}
+		a.evaluate(record, data)
+	}
+	return a.result()
+}
+
+func TestAssertionsHold(t *testing.T) {
+	tests := []string{
+		`record where id == 1 exists`,
+		`record where name == "Papilio canadensis" has id == 2`,
+		`record where name == "Papilio canadensis" has all fields equal to [2, "Papilio canadensis", true, "1855-02-26T00:00:00Z"]`,
+		`record where id == 4 is invalid`,
+		`record where id == 1 is valid`,
+		`record where "last spotted" == "1963-04-29T00:00:00Z" has id == 1`,
+		`record where extinct == true and id == 3 has name == "52" and "last spotted" == null`,
+		`no record has null in column id`,
+		`no record has null in name`,
+		`column id is always within [1, 4]`,
+		`column id is always within [-1e3, 1e3]`,
+		`exactly 2 records match extinct == true`,
+		`exactly 1 record matches extinct == true and id == 2`,
+		`exactly 0 records match name == "Vulpes chama"`,
+		`exactly 1 record is invalid`,
+	}
+	for _, text := range tests {
+		if err := evaluateAssertion(t, text); err != nil {
+			t.Errorf("%s: %s", text, err)
+		}
+	}
+}
+
+func TestAssertionsFail(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{`record where id == 9 exists`, "no matching record was published"},
+		// Numbers and strings are different values, even if they print the same.
+		{`record where name == 52 exists`, "no matching record was published"},
+		{`record where id == "1" exists`, "no matching record was published"},
+		{`record where id == 1 has name == "x"`, `1 of 1 matching records didn't: [1, "Lamprotornis nitens", false, "1963-04-29T00:00:00Z"] has name == Lamprotornis nitens`},
+		{`record where id == 1 has all fields equal to [1, "Lamprotornis nitens", false]`, "has 4 fields, not 3"},
+		{`record where id == 1 is invalid`, "1 of 1 matching records didn't"},
+		{`no record has null in column extinct`, "1 records had null"},
+		{`column id is always within [1, 3]`, "1 values were out of range: 4"},
+		{`column name is always within [0, 100]`, "4 values were out of range"},
+		{`exactly 3 records match extinct == true`, "2 records matched"},
+		{`exactly 0 records are invalid`, "1 records matched"},
+	}
+	for _, test := range tests {
+		err := evaluateAssertion(t, test.text)
+		if err == nil {
+			t.Errorf("%s: held, but it shouldn't have", test.text)
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %q, want %q", test.text, err, test.err)
+		}
+	}
+}
+
+func TestParseAssertion(t *testing.T) {
+	a, err := parseAssertion(`record where extinct == true and id == 3 has name == "52" and "last spotted" == null`, assertSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := a.(*recordAssertion)
+	// "and" binds the terms on each side of "has" into separate conditions.
+	if len(r.where) != 2 || r.where[0].name != "extinct" || r.where[1].name != "id" {
+		t.Errorf("where = %+v, want extinct and id", r.where)
+	}
+	if len(r.fields) != 2 || r.fields[0].name != "name" || r.fields[1].index != 3 || r.fields[1].value != nil {
+		t.Errorf("fields = %+v, want name and last spotted", r.fields)
+	}
+	if r.fields[0].value != "52" {
+		t.Errorf("quoted 52 = %#v, want the string", r.fields[0].value)
+	}
+	if r.where[1].value != float64(3) {
+		t.Errorf("3 = %#v, want a number", r.where[1].value)
+	}
+
+	a, err = parseAssertion(`record where name == "say \"hi\", \\ok" exists`, assertSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := a.(*recordAssertion).where[0].value; v != `say "hi", \ok` {
+		t.Errorf("escaped string = %q", v)
+	}
+}
+
+func TestParseAssertionErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{``, `expected "record", "no", "column" or "exactly" at column 1, got the end`},
+		{`records where id == 1 exists`, `expected "record", "no", "column" or "exactly" at column 1, got records`},
+		{`record where id = 1 exists`, `unexpected "=" at column 17`},
+		{`record where id == 1`, `expected "exists", "is" or "has" at column 21, got the end`},
+		{`record where id == 1 exists now`, `expected the end of the assertion at column 29, got now`},
+		{`record where nope == 1 exists`, `schema animals has no column "nope" (at column 14)`},
+		{`record where last spotted == 1 exists`, `schema animals has no column "last" (at column 14)`},
+		{`record where name == "Vulpes exists`, `unterminated string at column 22`},
+		{`record where id == 1.2.3 exists`, `bad number 1.2.3 at column 20`},
+		{`record where id == one exists`, `expected a value at column 20, got one`},
+		{`record where id == 1 has all fields equal to [1, 2`, `expected "," at column 51, got the end`},
+		{`record where id == 1 is broken`, `expected "valid" at column 25, got broken`},
+		{`no record has null in column`, `expected a column at column 29, got the end`},
+		{`column id is always within [5, 1]`, `the range [5, 1] is empty`},
+		{`column id is always within [a, 1]`, `expected a number at column 29, got a`},
+		{`exactly 1.5 records match id == 1`, `1.5 isn't a number of records`},
+		{`exactly 2 records were invalid`, `expected "match" or "are invalid" at column 19, got were`},
+	}
+	for _, test := range tests {
+		_, err := parseAssertion(test.text, assertSchema)
+		if err == nil {
+			t.Errorf("%q: parsed, but it shouldn't have", test.text)
+		} else if err.Error() != test.err {
+			t.Errorf("%q: got error %q, want %q", test.text, err, test.err)
+		}
+	}
+}
+
+func TestValuesEqual(t *testing.T) {
+	tests := []struct {
+		want, got interface{}
+		equal     bool
+	}{
+		{float64(1), float64(1), true},
+		{float64(27.78092), float64(27.780920000001), true},
+		{float64(1), float64(1.1), false},
+		{float64(1), "1", false},
+		{"1", float64(1), false},
+		{"a", "a", true},
+		{"2018-02-05T20:30:25Z", "2018-02-05T20:30:25.000Z", true},
+		{"2018-02-05T20:30:25Z", "2018-02-05T21:30:25+01:00", true},
+		{"2018-02-05T20:30:25Z", "2018-02-05T20:30:26Z", false},
+		{true, true, true},
+		{true, "true", false},
+		{nil, nil, true},
+		{nil, float64(0), false},
+	}
+	for _, test := range tests {
+		if equal := valuesEqual(test.want, test.got); equal != test.equal {
+			t.Errorf("valuesEqual(%#v, %#v) = %v, want %v", test.want, test.got, equal, test.equal)
+		}
+	}
+}
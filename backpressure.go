@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// backpressureTestCase publishes a large file while reading the stream slowly,
+// and watches the plugin's memory. A plugin which respects gRPC flow control
+// stops reading the file when the consumer falls behind, so its memory stays
+// flat; a plugin which doesn't keeps reading and buffers everything it can't send.
+type backpressureTestCase struct {
+	n string
+	d string
+	// rows is the number of rows in the generated file.
+	rows int
+	// recvDelay is how long the host waits before receiving each record.
+	recvDelay time.Duration
+	// duration is how long the host keeps receiving before giving up on the stream.
+	duration time.Duration
+	// maxGrowth is how many bytes the plugin's memory may grow by during the publish.
+	maxGrowth int64
+	// discoverTimeout replaces the configured timeout, since the file is large
+	// and this test isn't about how fast discovery is.
+	discoverTimeout time.Duration
+}
+
+func (t *backpressureTestCase) name() string {
+	return t.n
+}
+
+func (t *backpressureTestCase) description() string {
+	return t.d
+}
+
+func (t *backpressureTestCase) exclusive() {}
+
+func (t *backpressureTestCase) execute(client plugin.PluginClient, result *testResult) *testResult {
+	if _, err := readProcSample(activePlugin.pid()); err != nil {
+		result.comment("%s", color.YellowString("can't measure plugin memory, skipping: %s", err))
+		return result
+	}
+
+	dir, err := ioutil.TempDir("", "backpressure")
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "couldn't create temp dir"))
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "people.large.csv")
+	size, err := writePeopleFile(file, t.rows)
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "couldn't generate data"))
+	}
+	result.log("generated %d rows (%s) in %s", t.rows, formatBytes(size), file)
+
+	settings := &plugin.Settings{
+		FileGlob: file,
+	}
+	discover, err := discoverSchemas(client, settings, t.discoverTimeout, result.log)
+	if err != nil {
+		return result.withErr(errors.WithMessage(err, "discovery failed"))
+	}
+	if len(discover.Schemas) != 1 {
+		return result.withErr(errors.Errorf("expected 1 schema, got %d", len(discover.Schemas)))
+	}
+
+	baseline, err := readProcSample(activePlugin.pid())
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "couldn't measure plugin memory"))
+	}
+	result.log("plugin memory before publish: %s", formatBytes(baseline.rss))
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.duration)
+	defer cancel()
+	stream, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   discover.Schemas[0],
+	})
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "publish failed"))
+	}
+
+	peakCh := make(chan int64, 1)
+	go sampleMemory(ctx, peakCh)
+
+	count := 0
+	for {
+		time.Sleep(t.recvDelay)
+		record, err := stream.Recv()
+		if err == io.EOF || ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			return result.withErr(errors.Errorf("publish error on record %d: %s", count, err))
+		}
+		if record.Progress == nil {
+			count++
+		}
+	}
+	cancel()
+	peak := <-peakCh
+
+	growth := peak - baseline.rss
+	result.log("received %d records slowly; plugin memory peaked at %s", count, formatBytes(peak))
+	result.comment("plugin memory grew by %s (from %s to %s) while the host slowly received %d of %d records",
+		formatBytes(growth), formatBytes(baseline.rss), formatBytes(peak), count, t.rows)
+
+	if growth > t.maxGrowth {
+		return result.withErr(errors.Errorf("plugin memory grew by %s, more than the limit of %s; it should stop reading when the host can't keep up", formatBytes(growth), formatBytes(t.maxGrowth)))
+	}
+	result.comment("%s", color.GreenString("plugin respected flow control"))
+	return result
+}
+
+// sampleMemory samples the plugin's memory until ctx is done, then sends the peak.
+func sampleMemory(ctx context.Context, peakCh chan int64) {
+	var peak int64
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if sample, err := readProcSample(activePlugin.pid()); err == nil && sample.rss > peak {
+			peak = sample.rss
+		}
+		select {
+		case <-ctx.Done():
+			peakCh <- peak
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writePeopleFile writes a CSV file in the same format as data/people.*.csv,
+// returning its size.
+func writePeopleFile(path string, rows int) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	r := rand.New(rand.NewSource(1))
+	genders := []string{"Female", "Male"}
+	w := bufio.NewWriter(f)
+	fmt.Fprintln(w, "id,first_name,last_name,email,gender,ip_address")
+	for i := 1; i <= rows; i++ {
+		first := randomName(r)
+		last := randomName(r)
+		fmt.Fprintf(w, "%d,%s,%s,%s.%s%d@example.com,%s,%d.%d.%d.%d\n",
+			i, first, last, first, last, i, genders[r.Intn(2)],
+			r.Intn(256), r.Intn(256), r.Intn(256), r.Intn(256))
+	}
+	if err := w.Flush(); err != nil {
+		return 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func randomName(r *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 4+r.Intn(6))
+	for i := range b {
+		b[i] = letters[r.Intn(len(letters))]
+	}
+	b[0] -= 'a' - 'A'
+	return string(b)
+}
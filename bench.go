@@ -0,0 +1,306 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// benchConfig is the configuration of the bench command.
+type benchConfig struct {
+	rows       int
+	files      int
+	iterations int
+	dir        string
+	baseline   string
+	tolerance  float64
+	update     bool
+	timeout    time.Duration
+}
+
+// benchResult is the outcome of a benchmark, which is stored as the baseline.
+// Latencies and throughputs are the medians over all the iterations.
+type benchResult struct {
+	Rows             int       `json:"rows"`
+	Files            int       `json:"files"`
+	Iterations       int       `json:"iterations"`
+	DiscoverSeconds  float64   `json:"discoverSeconds"`
+	PublishSeconds   float64   `json:"publishSeconds"`
+	RecordsPerSecond float64   `json:"recordsPerSecond"`
+	BytesPerSecond   float64   `json:"bytesPerSecond"`
+	Recorded         time.Time `json:"recorded"`
+}
+
+// parseBenchArgs parses the arguments to the bench command,
+// returning the remaining arguments (the plugin command) and
+// the command which runs the benchmark.
+func parseBenchArgs(args []string) ([]string, command) {
+	c := &benchConfig{}
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	fs.IntVar(&c.rows, "rows", 2000000, "number of rows in each generated file")
+	fs.IntVar(&c.files, "files", 2, "number of generated files")
+	fs.IntVar(&c.iterations, "iterations", 5, "number of times to discover and publish the files")
+	fs.StringVar(&c.dir, "dir", filepath.Join(os.TempDir(), "plugin-bench"), "directory for the generated files, which are reused between runs")
+	fs.StringVar(&c.baseline, "baseline", "bench.json", "file the results are compared with, and saved to if it doesn't exist")
+	fs.Float64Var(&c.tolerance, "tolerance", 0.2, "fraction by which a result may be worse than the baseline before it is a regression")
+	fs.BoolVar(&c.update, "update", false, "save the results as the new baseline")
+	fs.DurationVar(&c.timeout, "timeout", 10*time.Minute, "deadline for each discover and publish, replacing -discover-timeout and -publish-timeout")
+	fs.Parse(args)
+
+	if c.rows < 1 || c.files < 1 || c.iterations < 1 {
+		fs.Usage()
+		log.Fatal("-rows, -files and -iterations must be at least 1")
+	}
+
+	return fs.Args(), func(client plugin.PluginClient) error {
+		return runBench(client, c)
+	}
+}
+
+// runBench generates the fixtures, measures discover and publish over
+// several iterations, and compares the medians with the baseline.
+func runBench(client plugin.PluginClient, c *benchConfig) error {
+	glob, size, err := generateBenchFiles(c)
+	if err != nil {
+		err = errors.WithMessage(err, "couldn't generate data")
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+	settings := &plugin.Settings{
+		FileGlob: glob,
+	}
+
+	var discovers, publishes []time.Duration
+	for i := 1; i <= c.iterations; i++ {
+		log.Printf("iteration %d/%d: discovering...", i, c.iterations)
+		start := time.Now()
+		discover, err := discoverSchemas(client, settings, c.timeout, log.Printf)
+		if err != nil {
+			err = errors.WithMessage(err, "discovery failed")
+			log.Print(color.RedString(err.Error()))
+			return err
+		}
+		discovers = append(discovers, time.Since(start))
+		if len(discover.Schemas) != 1 {
+			err = errors.Errorf("expected 1 schema, got %d", len(discover.Schemas))
+			log.Print(color.RedString(err.Error()))
+			return err
+		}
+
+		log.Printf("iteration %d/%d: publishing...", i, c.iterations)
+		start = time.Now()
+		count, err := benchPublish(client, settings, discover.Schemas[0], c.timeout)
+		if err != nil {
+			err = errors.WithMessage(err, "publish failed")
+			log.Print(color.RedString(err.Error()))
+			return err
+		}
+		publishes = append(publishes, time.Since(start))
+		if want := c.rows * c.files; count != want {
+			err = errors.Errorf("publish returned %d records, wanted %d", count, want)
+			log.Print(color.RedString(err.Error()))
+			return err
+		}
+	}
+
+	records := float64(c.rows * c.files)
+	discover := median(discovers)
+	publish := median(publishes)
+	current := &benchResult{
+		Rows:             c.rows,
+		Files:            c.files,
+		Iterations:       c.iterations,
+		DiscoverSeconds:  discover.Seconds(),
+		PublishSeconds:   publish.Seconds(),
+		RecordsPerSecond: records / publish.Seconds(),
+		BytesPerSecond:   float64(size) / publish.Seconds(),
+		Recorded:         time.Now().UTC(),
+	}
+	renderBench(discovers, publishes, current)
+
+	baseline, err := readBaseline(c.baseline)
+	switch {
+	case c.update:
+		return saveBaseline(c.baseline, current)
+	case os.IsNotExist(errors.Cause(err)):
+		log.Printf("no baseline in %s yet", c.baseline)
+		return saveBaseline(c.baseline, current)
+	case err != nil:
+		err = errors.WithMessage(err, "couldn't read baseline")
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+
+	return compareBench(baseline, current, c.tolerance)
+}
+
+// generateBenchFiles writes the fixtures into c.dir, unless they are already
+// there, and returns a glob matching them and their total size.
+func generateBenchFiles(c *benchConfig) (string, int64, error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return "", 0, err
+	}
+	// The row count is part of the name, so changing -rows never reuses the wrong files.
+	prefix := fmt.Sprintf("people.bench%d", c.rows)
+	paths := map[string]bool{}
+	for i := 1; i <= c.files; i++ {
+		paths[filepath.Join(c.dir, fmt.Sprintf("%s.%d.csv", prefix, i))] = true
+	}
+	// Remove files left by runs with more -files, which the glob would match.
+	old, _ := filepath.Glob(filepath.Join(c.dir, prefix+".*.csv"))
+	for _, f := range old {
+		if !paths[f] {
+			os.Remove(f)
+		}
+	}
+
+	var total int64
+	for i := 1; i <= c.files; i++ {
+		path := filepath.Join(c.dir, fmt.Sprintf("%s.%d.csv", prefix, i))
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+			continue
+		}
+		log.Printf("generating %d rows in %s...", c.rows, path)
+		// Write to a temporary file first, so an interrupted run doesn't leave a short file to reuse.
+		size, err := writePeopleFile(path+".tmp", c.rows)
+		if err != nil {
+			return "", 0, err
+		}
+		if err = os.Rename(path+".tmp", path); err != nil {
+			return "", 0, err
+		}
+		total += size
+	}
+	return filepath.Join(c.dir, prefix+".*.csv"), total, nil
+}
+
+// benchPublish publishes schema and returns the number of records received.
+func benchPublish(client plugin.PluginClient, settings *plugin.Settings, schema *plugin.Schema, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	stream, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   schema,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return count, errors.Wrapf(err, "error after %d records", count)
+		}
+		if record.Progress == nil {
+			count++
+		}
+	}
+}
+
+func renderBench(discovers, publishes []time.Duration, r *benchResult) {
+	color.Blue("BENCHMARK %d files of %d rows", r.Files, r.Rows)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  iteration\tdiscover\tpublish")
+	for i := range discovers {
+		fmt.Fprintf(w, "  %d\t%s\t%s\n", i+1, discovers[i].Round(time.Millisecond), publishes[i].Round(time.Millisecond))
+	}
+	w.Flush()
+	fmt.Printf("  median discover latency: %s\n", seconds(r.DiscoverSeconds))
+	fmt.Printf("  median publish time:     %s (%.0f records/s, %s/s)\n", seconds(r.PublishSeconds), r.RecordsPerSecond, formatBytes(int64(r.BytesPerSecond)))
+}
+
+// compareBench reports how current compares with baseline,
+// returning an error if anything got worse by more than tolerance.
+func compareBench(baseline, current *benchResult, tolerance float64) error {
+	if baseline.Rows != current.Rows || baseline.Files != current.Files {
+		log.Print(color.YellowString("baseline was recorded with %d files of %d rows, not %d of %d; use -update to replace it",
+			baseline.Files, baseline.Rows, current.Files, current.Rows))
+		return nil
+	}
+
+	color.Blue("COMPARED WITH BASELINE FROM %s", baseline.Recorded.Format(time.RFC3339))
+	regressions := 0
+	compare := func(name string, base, now float64, lowerIsBetter bool, format func(float64) string) {
+		if base == 0 {
+			// A zero baseline, from an edited file or a run which measured nothing, can't be compared with.
+			fmt.Printf("  %-18s %s -> %s (no baseline to compare with)\n", name, format(base), format(now))
+			return
+		}
+		change := (now - base) / base
+		worse := change
+		if !lowerIsBetter {
+			worse = -change
+		}
+		line := fmt.Sprintf("  %-18s %s -> %s (%+.1f%%)", name, format(base), format(now), change*100)
+		switch {
+		case worse > tolerance:
+			regressions++
+			color.Red("%s regression", line)
+		case worse < -tolerance:
+			color.Green("%s improvement", line)
+		default:
+			fmt.Println(line)
+		}
+	}
+	compare("discover latency", baseline.DiscoverSeconds, current.DiscoverSeconds, true, seconds)
+	compare("publish records/s", baseline.RecordsPerSecond, current.RecordsPerSecond, false, func(f float64) string { return fmt.Sprintf("%.0f", f) })
+	compare("publish bytes/s", baseline.BytesPerSecond, current.BytesPerSecond, false, func(f float64) string { return formatBytes(int64(f)) + "/s" })
+
+	if regressions > 0 {
+		err := errors.Errorf("%d results regressed by more than %.0f%%", regressions, tolerance*100)
+		color.New(color.Bold, color.FgRed).Println(err)
+		return err
+	}
+	color.New(color.Bold, color.FgGreen).Println("NO REGRESSIONS")
+	return nil
+}
+
+func readBaseline(path string) (*benchResult, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := new(benchResult)
+	if err = json.Unmarshal(b, r); err != nil {
+		return nil, errors.Wrapf(err, "bad baseline in %s", path)
+	}
+	return r, nil
+}
+
+func saveBaseline(path string, r *benchResult) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return errors.Wrap(err, "couldn't save baseline")
+	}
+	log.Printf("saved results as the baseline in %s", path)
+	return nil
+}
+
+func median(durations []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return percentile(sorted, 50)
+}
+
+func seconds(s float64) string {
+	return time.Duration(s * float64(time.Second)).Round(time.Millisecond).String()
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// cancellationTestCase cancels a Publish part way through a large file and
+// checks that the plugin notices: it should stop reading the file, stop
+// using CPU and stop logging, and it should still be able to answer a
+// Discover call afterwards. The host can't tell what a line of output is
+// about, so anything the plugin logs in the window counts against it.
+type cancellationTestCase struct {
+	n string
+	d string
+	// rows is the number of rows in the generated file.
+	rows int
+	// cancelAfter is the number of records to receive before cancelling.
+	cancelAfter int
+	// grace is how long the plugin has to stop after the cancellation.
+	grace time.Duration
+	// window is how long the plugin is watched after the grace period.
+	window time.Duration
+	// maxCPU is the fraction of a CPU the plugin may use during the window.
+	maxCPU float64
+	// maxRead is the number of bytes the plugin may read during the window.
+	maxRead int64
+	// discoverTimeout replaces the configured timeout for the large file.
+	discoverTimeout time.Duration
+	// glob and expectedSchema are used for the Discover after the cancellation.
+	glob           string
+	expectedSchema plugin.Schema
+}
+
+func (t *cancellationTestCase) name() string {
+	return t.n
+}
+
+func (t *cancellationTestCase) description() string {
+	return t.d
+}
+
+func (t *cancellationTestCase) exclusive() {}
+
+func (t *cancellationTestCase) execute(client plugin.PluginClient, result *testResult) *testResult {
+	dir, err := ioutil.TempDir("", "cancellation")
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "couldn't create temp dir"))
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "people.large.csv")
+	size, err := writePeopleFile(file, t.rows)
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "couldn't generate data"))
+	}
+	result.log("generated %d rows (%s) in %s", t.rows, formatBytes(size), file)
+
+	settings := &plugin.Settings{
+		FileGlob: file,
+	}
+	discover, err := discoverSchemas(client, settings, t.discoverTimeout, result.log)
+	if err != nil {
+		return result.withErr(errors.WithMessage(err, "discovery failed"))
+	}
+	if len(discover.Schemas) != 1 {
+		return result.withErr(errors.Errorf("expected 1 schema, got %d", len(discover.Schemas)))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   discover.Schemas[0],
+	})
+	if err != nil {
+		return result.withErr(errors.Wrap(err, "publish failed"))
+	}
+
+	count := 0
+	for count < t.cancelAfter {
+		record, err := stream.Recv()
+		if err != nil {
+			return result.withErr(errors.Errorf("publish ended after %d records, before it could be cancelled: %s", count, err))
+		}
+		if record.Progress == nil {
+			count++
+		}
+	}
+	cancel()
+	result.log("cancelled publish after %d records, waiting %s for the plugin to stop...", count, t.grace)
+	time.Sleep(t.grace)
+
+	lines := pluginOutput.count()
+	before, procErr := readProcSample(activePlugin.pid())
+	time.Sleep(t.window)
+	after, _ := readProcSample(activePlugin.pid())
+
+	var failures []string
+	if procErr != nil {
+		result.comment("%s", color.YellowString("can't measure plugin CPU or reads: %s", procErr))
+	} else {
+		cpu := float64(after.cpu-before.cpu) / float64(t.window)
+		read := after.readBytes - before.readBytes
+		result.comment("in the %s after the grace period the plugin used %.0f%% CPU and read %s", t.window, cpu*100, formatBytes(read))
+		if cpu > t.maxCPU {
+			failures = append(failures, "it was still using CPU")
+		}
+		if read > t.maxRead {
+			failures = append(failures, "it was still reading")
+		}
+	}
+	// Any output counts, not only output about the publish (see cancellationTestCase).
+	if output := pluginOutput.since(lines); len(output) > 0 {
+		logged := len(output)
+		if logged > 10 {
+			output = output[logged-10:]
+		}
+		result.comment("%s", color.RedString("plugin logged %d lines after the grace period, ending with:\n    %s", pluginOutput.count()-lines, strings.Join(output, "\n    ")))
+		failures = append(failures, "it was still logging")
+	}
+	if len(failures) > 0 {
+		return result.withErr(errors.Errorf("plugin did not stop publishing within %s of the cancellation: %s", t.grace, strings.Join(failures, ", ")))
+	}
+	result.comment("%s", color.GreenString("plugin stopped publishing when the publish was cancelled"))
+
+	result.log("checking that discover still works...")
+	discover, err = discoverSchemas(client, &plugin.Settings{FileGlob: t.glob}, discoverTimeout, result.log)
+	if err != nil {
+		return result.withErr(errors.WithMessage(err, "discovery after cancellation failed"))
+	}
+	if namesMatch, _, _ := checkSchemaIn(t.expectedSchema, discover.Schemas); !namesMatch {
+		return result.withErr(errors.Errorf("discovery after cancellation did not find schema %q (got: %s)", t.expectedSchema.Name, discover.Schemas))
+	}
+	result.comment("%s", color.GreenString("discover still works after the cancellation"))
+
+	return result
+}
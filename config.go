@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"os"
+	"strings"
+	"time"
+)
+
+// These settings control how the host talks to the plugin. Each of them
+// can be set by a command line flag, or by an environment variable named
+// after the flag (for example, -publish-timeout can be set using HOST_PUBLISH_TIMEOUT).
+// Flags take precedence over environment variables.
+var (
+	pluginStartupTimeout = 5 * time.Second
+	pluginStopTimeout    = 2 * time.Second
+	dialTimeout          = 1 * time.Second
+	discoverTimeout      = 1 * time.Second
+	publishTimeout       = 2 * time.Second
+	maxMessageSize       = 4 * 1024 * 1024
+	readBufferSize       = 500
+	writeBufferSize      = 32 * 1024
+	keepaliveTime        time.Duration
+	keepaliveTimeout     = 20 * time.Second
+)
+
+const envPrefix = "HOST_"
+
+// configSources records where the value of each flag came from.
+var configSources = map[string]string{}
+
+func init() {
+	flag.DurationVar(&pluginStartupTimeout, "startup-timeout", pluginStartupTimeout, "how long to wait for the plugin to write its port")
+	flag.DurationVar(&pluginStopTimeout, "stop-timeout", pluginStopTimeout, "how long the plugin has to exit after SIGINT when the host is finished with it")
+	flag.DurationVar(&dialTimeout, "dial-timeout", dialTimeout, "how long to wait to connect to the plugin")
+	flag.DurationVar(&discoverTimeout, "discover-timeout", discoverTimeout, "deadline for discovering all the schemas in a test")
+	flag.DurationVar(&publishTimeout, "publish-timeout", publishTimeout, "deadline for publishing all the records in a test")
+	flag.IntVar(&maxMessageSize, "max-message-size", maxMessageSize, "largest message (in bytes) the host will accept from the plugin")
+	flag.IntVar(&readBufferSize, "read-buffer-size", readBufferSize, "size (in bytes) of the connection's read buffer")
+	flag.IntVar(&writeBufferSize, "write-buffer-size", writeBufferSize, "size (in bytes) of the connection's write buffer")
+	flag.DurationVar(&keepaliveTime, "keepalive-time", keepaliveTime, "ping the plugin after this long without activity (0 disables keepalive)")
+	flag.DurationVar(&keepaliveTimeout, "keepalive-timeout", keepaliveTimeout, "close the connection if a keepalive ping isn't answered within this long")
+
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: %s [flags] [profile [-glob glob] | bench [bench flags]] <plugin command> [plugin args...]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s [flags] diff [-glob glob] [-limit n] <plugin A command> [args...] -- <plugin B command> [args...]\n", os.Args[0])
+		fmt.Fprintf(out, "       %s generate -spec spec.json [-out dir]\n\n", os.Args[0])
+		fmt.Fprintf(out, "flags (each can also be set with an environment variable, like %sPUBLISH_TIMEOUT):\n", envPrefix)
+		flag.PrintDefaults()
+	}
+}
+
+// parseConfig parses the command line flags, then fills in any flags
+// which weren't set on the command line from the environment.
+func parseConfig() {
+	flag.Parse()
+
+	flag.Visit(func(f *flag.Flag) {
+		configSources[f.Name] = "flag"
+	})
+	flag.VisitAll(func(f *flag.Flag) {
+		if _, ok := configSources[f.Name]; ok {
+			return
+		}
+		name := envName(f.Name)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			configSources[f.Name] = "default"
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			log.Fatalf("invalid value %q for %s: %s", value, name, err)
+		}
+		configSources[f.Name] = "env " + name
+	})
+}
+
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// printConfig logs the effective value of every setting and where it came from.
+func printConfig() {
+	log.Printf("configuration:")
+	flag.VisitAll(func(f *flag.Flag) {
+		log.Printf("  %-20s %-12s %s", f.Name, f.Value, color.New(color.Faint).Sprint(configSources[f.Name]))
+	})
+}
+
+// dialOptions returns the options for connecting to the plugin, based on the configuration.
+func dialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithReadBufferSize(readBufferSize),
+		grpc.WithWriteBufferSize(writeBufferSize),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)),
+	}
+	if keepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                keepaliveTime,
+			Timeout:             keepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+	return opts
+}
@@ -0,0 +1,440 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// comparedPlugin is the second plugin started by the diff command, which is
+// killed along with the active plugin if the user exits.
+var comparedPlugin *pluginProcess
+
+// diffConfig is the configuration of the diff command.
+type diffConfig struct {
+	glob  string
+	limit int
+	// a and b are the commands of the two plugins, for the report.
+	a, b string
+}
+
+// parseDiffArgs parses the arguments to the diff command, returning
+// the command of the first plugin and the command which compares it
+// with the second plugin. The plugin commands are separated by "--".
+func parseDiffArgs(args []string) ([]string, command) {
+	pwd, _ := os.Getwd()
+	c := &diffConfig{}
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.StringVar(&c.glob, "glob", filepath.Join(pwd, "data", "*.csv"), "file glob to discover and publish from both plugins")
+	fs.IntVar(&c.limit, "limit", 20, "number of differences listed for each schema")
+	fs.Parse(args)
+
+	var a, b []string
+	for i, arg := range fs.Args() {
+		if arg == "--" {
+			a, b = fs.Args()[:i], fs.Args()[i+1:]
+			break
+		}
+	}
+	if len(a) == 0 || len(b) == 0 {
+		flag.Usage()
+		log.Fatal("expected two plugin commands separated by --, like: diff ./old -- ./new")
+	}
+	c.a, c.b = strings.Join(a, " "), strings.Join(b, " ")
+
+	return a, func(client plugin.PluginClient) error {
+		return runDiff(client, b, c)
+	}
+}
+
+// runDiff starts the second plugin, makes the same Discover and Publish
+// calls to both, and reports how their responses differ.
+func runDiff(clientA plugin.PluginClient, argsB []string, c *diffConfig) error {
+	log.Printf("starting second plugin: %s", c.b)
+	p, err := startPlugin(argsB)
+	if err != nil {
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+	comparedPlugin = p
+	defer p.stop()
+	clientB, err := connect(p.port)
+	if err != nil {
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+
+	settings := &plugin.Settings{
+		FileGlob: c.glob,
+	}
+	log.Printf("comparing %s (A) with %s (B) on %s", c.a, c.b, c.glob)
+	discoverA, err := discoverSchemas(clientA, settings, discoverTimeout, prefixLog("A: "))
+	if err != nil {
+		err = errors.WithMessage(err, "discovery failed on A")
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+	discoverB, err := discoverSchemas(clientB, settings, discoverTimeout, prefixLog("B: "))
+	if err != nil {
+		err = errors.WithMessage(err, "discovery failed on B")
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+
+	total := 0
+	for _, pair := range pairSchemas(discoverA.Schemas, discoverB.Schemas) {
+		r := &diffReport{limit: c.limit}
+		a, b := pair.a, pair.b
+		switch {
+		case b == nil:
+			r.add("only discovered by A")
+		case a == nil:
+			r.add("only discovered by B")
+		default:
+			if err = diffSchema(r, clientA, clientB, settings, a, b); err != nil {
+				log.Print(color.RedString(err.Error()))
+				return err
+			}
+		}
+		r.print(pair.name())
+		total += r.count
+	}
+
+	if total > 0 {
+		err = errors.Errorf("found %d differences between %s and %s", total, c.a, c.b)
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+	log.Print(color.GreenString("no differences between %s and %s", c.a, c.b))
+	return nil
+}
+
+func prefixLog(prefix string) func(format string, args ...interface{}) {
+	return func(format string, args ...interface{}) {
+		log.Printf(prefix+format, args...)
+	}
+}
+
+// schemaPair is a schema discovered by A and the schema discovered by B for the
+// same data. Either is nil if only one plugin discovered it.
+type schemaPair struct {
+	a, b *plugin.Schema
+}
+
+func (p schemaPair) name() string {
+	switch {
+	case p.a == nil:
+		return p.b.Name
+	case p.b == nil || p.b.Name == p.a.Name:
+		return p.a.Name
+	}
+	return fmt.Sprintf("%s (%s in B)", p.a.Name, p.b.Name)
+}
+
+// pairSchemas pairs each of A's schemas with the schema of B which has the most
+// properties with the same names, preferring one with the same name, because
+// plugins choose their own schema names. Schemas which share no properties
+// with any of the other plugin's are paired by name.
+func pairSchemas(schemasA, schemasB []*plugin.Schema) []schemaPair {
+	sortedA := append([]*plugin.Schema(nil), schemasA...)
+	sort.Slice(sortedA, func(i, j int) bool {
+		return sortedA[i].Name < sortedA[j].Name
+	})
+	unpaired := append([]*plugin.Schema(nil), schemasB...)
+
+	var pairs []schemaPair
+	for _, a := range sortedA {
+		best, bestScore := -1, 0
+		for j, b := range unpaired {
+			score := 2 * sharedProperties(a, b)
+			if b.Name == a.Name {
+				score++
+			}
+			if score > bestScore {
+				best, bestScore = j, score
+			}
+		}
+		pair := schemaPair{a: a}
+		if best >= 0 {
+			pair.b = unpaired[best]
+			unpaired = append(unpaired[:best], unpaired[best+1:]...)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(unpaired, func(i, j int) bool {
+		return unpaired[i].Name < unpaired[j].Name
+	})
+	for _, b := range unpaired {
+		pairs = append(pairs, schemaPair{b: b})
+	}
+	return pairs
+}
+
+// sharedProperties is the number of a's properties which have the same name as one of b's.
+func sharedProperties(a, b *plugin.Schema) int {
+	names := map[string]bool{}
+	for _, p := range b.Properties {
+		names[normalizeName(p.Name)] = true
+	}
+	n := 0
+	for _, p := range a.Properties {
+		if names[normalizeName(p.Name)] {
+			n++
+		}
+	}
+	return n
+}
+
+func schemasByName(schemas []*plugin.Schema) map[string]*plugin.Schema {
+	m := map[string]*plugin.Schema{}
+	for _, s := range schemas {
+		m[s.Name] = s
+	}
+	return m
+}
+
+// diffReport collects the differences found in a schema.
+type diffReport struct {
+	limit int
+	count int
+	lines []string
+}
+
+func (r *diffReport) add(format string, args ...interface{}) {
+	r.count++
+	if len(r.lines) < r.limit {
+		r.lines = append(r.lines, fmt.Sprintf(format, args...))
+	}
+}
+
+func (r *diffReport) print(name string) {
+	color.Blue("DIFF schema %s", name)
+	if r.count == 0 {
+		fmt.Printf("  %s\n", color.GreenString("identical"))
+		return
+	}
+	for _, line := range r.lines {
+		fmt.Printf("  %s\n", color.RedString("%s", line))
+	}
+	if r.count > len(r.lines) {
+		fmt.Printf("  ...and %d more differences\n", r.count-len(r.lines))
+	}
+}
+
+// diffSchema compares the properties of a schema discovered by both plugins,
+// then publishes it from both and compares the records.
+func diffSchema(r *diffReport, clientA, clientB plugin.PluginClient, settings *plugin.Settings, a, b *plugin.Schema) error {
+	// bIndex maps the index of each of A's properties to the index of B's property with
+	// the same name, or -1. Names are normalized, as they are for pairing schemas.
+	bIndex := make([]int, len(a.Properties))
+	for i, pa := range a.Properties {
+		bIndex[i] = -1
+		for j, pb := range b.Properties {
+			if normalizeName(pb.Name) == normalizeName(pa.Name) {
+				bIndex[i] = j
+			}
+		}
+		switch j := bIndex[i]; {
+		case j < 0:
+			r.add("property %q was only discovered by A", pa.Name)
+		case j != i:
+			r.add("property %q is at index %d in A but %d in B", pa.Name, i, j)
+		}
+		if j := bIndex[i]; j >= 0 {
+			pb := b.Properties[j]
+			if pa.Name != pb.Name {
+				r.add("property %q is named %q in B", pa.Name, pb.Name)
+			}
+			if pa.Type != pb.Type {
+				r.add("property %q has type %q in A but %q in B", pa.Name, pa.Type, pb.Type)
+			}
+			if pa.IsKey != pb.IsKey {
+				r.add("property %q is a key in %s", pa.Name, map[bool]string{true: "A but not B", false: "B but not A"}[pa.IsKey])
+			}
+		}
+	}
+	for _, pb := range b.Properties {
+		found := false
+		for _, pa := range a.Properties {
+			found = found || normalizeName(pa.Name) == normalizeName(pb.Name)
+		}
+		if !found {
+			r.add("property %q was only discovered by B", pb.Name)
+		}
+	}
+
+	rowsA, err := publishRows(clientA, settings, a, plugin.RecordOrder_RECORD_ORDER_UNSPECIFIED)
+	if err != nil {
+		return errors.WithMessage(err, "publish of "+a.Name+" failed on A")
+	}
+	rowsB, err := publishRows(clientB, settings, b, plugin.RecordOrder_RECORD_ORDER_UNSPECIFIED)
+	if err != nil {
+		return errors.WithMessage(err, "publish of "+b.Name+" failed on B")
+	}
+	// Put B's data in the order of A's properties, so they can be compared by index.
+	for _, row := range rowsB {
+		aligned := make([]interface{}, len(bIndex))
+		for i, j := range bIndex {
+			aligned[i] = valueAt(row.data, j)
+		}
+		row.data = aligned
+	}
+
+	if len(rowsA) != len(rowsB) {
+		r.add("A published %d records but B published %d", len(rowsA), len(rowsB))
+	}
+	if key := diffKey(rowsA, len(a.Properties)); key >= 0 {
+		compareRowsByKey(r, a, key, rowsA, rowsB)
+	} else {
+		compareRowSets(r, rowsA, rowsB)
+	}
+	return nil
+}
+
+// diffRow is a published record and its data.
+type diffRow struct {
+	record *plugin.PublishRecord
+	data   []interface{}
+}
+
+func (row *diffRow) String() string {
+	b, _ := json.Marshal(row.data)
+	if row.record.Invalid {
+		return string(b) + " (invalid)"
+	}
+	return string(b)
+}
+
+// publishRows publishes schema, asking for the record order the caller needs, and
+// decodes the data of each record. The diff command pairs records by key, so it
+// doesn't need an order; repeated runs ask for the one set by -order.
+func publishRows(client plugin.PluginClient, settings *plugin.Settings, schema *plugin.Schema, order plugin.RecordOrder) ([]*diffRow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	stream, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   schema,
+		Order:    order,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []*diffRow
+	for {
+		record, err := stream.Recv()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "error after %d records", len(rows))
+		}
+		if record.Progress != nil {
+			continue
+		}
+		row := &diffRow{record: record}
+		json.Unmarshal([]byte(record.Data), &row.data)
+		rows = append(rows, row)
+	}
+}
+
+// diffKey returns the index of the first property whose values in rows are
+// all present and distinct, so rows can be paired by it, or -1 if there isn't one.
+func diffKey(rows []*diffRow, width int) int {
+	for i := 0; i < width; i++ {
+		seen := map[string]bool{}
+		unique := true
+		for _, row := range rows {
+			v := valueAt(row.data, i)
+			k := fmt.Sprint(v)
+			if v == nil || seen[k] {
+				unique = false
+				break
+			}
+			seen[k] = true
+		}
+		if unique && len(rows) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// compareRowsByKey pairs the rows from A and B by the value of the key
+// property and compares the values and invalid flags of each pair.
+func compareRowsByKey(r *diffReport, schema *plugin.Schema, key int, rowsA, rowsB []*diffRow) {
+	keyName := schema.Properties[key].Name
+	byKey := map[string][]*diffRow{}
+	for _, row := range rowsB {
+		k := fmt.Sprint(valueAt(row.data, key))
+		byKey[k] = append(byKey[k], row)
+	}
+
+	for _, a := range rowsA {
+		k := fmt.Sprint(valueAt(a.data, key))
+		matches := byKey[k]
+		if len(matches) == 0 {
+			r.add("record %s=%s was only published by A: %s", keyName, k, a)
+			continue
+		}
+		b := matches[0]
+		byKey[k] = matches[1:]
+
+		if a.record.Invalid != b.record.Invalid {
+			if a.record.Invalid {
+				r.add("record %s=%s was marked invalid by A (%s) but not B", keyName, k, a.record.Error)
+			} else {
+				r.add("record %s=%s was marked invalid by B (%s) but not A", keyName, k, b.record.Error)
+			}
+		}
+		for i, p := range schema.Properties {
+			va, vb := valueAt(a.data, i), valueAt(b.data, i)
+			if !valuesEqual(va, vb) {
+				ja, _ := json.Marshal(va)
+				jb, _ := json.Marshal(vb)
+				r.add("record %s=%s has %s %s in A but %s in B", keyName, k, p.Name, ja, jb)
+			}
+		}
+	}
+
+	var extra []*diffRow
+	for _, rows := range byKey {
+		extra = append(extra, rows...)
+	}
+	sort.Slice(extra, func(i, j int) bool {
+		return extra[i].String() < extra[j].String()
+	})
+	for _, b := range extra {
+		r.add("record %s=%v was only published by B: %s", keyName, valueAt(b.data, key), b)
+	}
+}
+
+// compareRowSets compares the rows from A and B as sets, for schemas
+// without a key property to pair them by.
+func compareRowSets(r *diffReport, rowsA, rowsB []*diffRow) {
+	strings := func(rows []*diffRow) []string {
+		var s []string
+		for _, row := range rows {
+			s = append(s, row.String())
+		}
+		sort.Strings(s)
+		return s
+	}
+	onlyA, onlyB := diffRecords(strings(rowsA), strings(rowsB))
+	for _, s := range onlyA {
+		r.add("record was only published by A: %s", s)
+	}
+	for _, s := range onlyB {
+		r.add("record was only published by B: %s", s)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"time"
+)
+
+// discoverProgressInterval limits how often discovery progress is logged.
+var discoverProgressInterval = 250 * time.Millisecond
+
+// discoverSchemas finds the schemas for settings using DiscoverStream, logging progress
+// as it arrives. If the plugin doesn't implement DiscoverStream it falls back to Discover.
+// The timeout applies to the whole discovery, not to each event.
+func discoverSchemas(client plugin.PluginClient, settings *plugin.Settings, timeout time.Duration, logf func(format string, args ...interface{})) (*plugin.DiscoverResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req := &plugin.DiscoverRequest{
+		Settings: settings,
+	}
+
+	stream, err := client.DiscoverStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemas []*plugin.Schema
+	var lastProgress time.Time
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if status.Code(err) == codes.Unimplemented {
+			logf("plugin does not implement DiscoverStream, using Discover")
+			return client.Discover(ctx, req)
+		}
+		if err != nil {
+			return nil, errors.Errorf("discover stream error after %d schemas: %s", len(schemas), err)
+		}
+
+		switch e := event.Event.(type) {
+		case *plugin.DiscoverEvent_Progress:
+			if time.Since(lastProgress) >= discoverProgressInterval {
+				lastProgress = time.Now()
+				logf("discover progress: %s", formatDiscoverProgress(e.Progress))
+			}
+		case *plugin.DiscoverEvent_Schema:
+			schemas = addOrReplaceSchema(schemas, e.Schema)
+			logf("discovered schema %q", e.Schema.Name)
+		default:
+			logf("ignoring unknown discover event %v", event)
+		}
+	}
+
+	return &plugin.DiscoverResponse{
+		Schemas: schemas,
+	}, nil
+}
+
+func formatDiscoverProgress(p *plugin.DiscoverProgress) string {
+	if p.FilesTotal > 0 {
+		return fmt.Sprintf("%d/%d files (%.0f%%) %s", p.FilesDone, p.FilesTotal, float64(p.FilesDone)/float64(p.FilesTotal)*100, p.CurrentFile)
+	}
+	return fmt.Sprintf("%d files %s", p.FilesDone, p.CurrentFile)
+}
+
+// addOrReplaceSchema replaces the schema in schemas which has the same name as schema,
+// or appends schema if there isn't one.
+func addOrReplaceSchema(schemas []*plugin.Schema, schema *plugin.Schema) []*plugin.Schema {
+	for i, s := range schemas {
+		if s.Name == schema.Name {
+			schemas[i] = schema
+			return schemas
+		}
+	}
+	return append(schemas, schema)
+}
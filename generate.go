@@ -0,0 +1,280 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// datasetSpec describes a data set for the generate command to produce.
+type datasetSpec struct {
+	// Name is the schema name; the files are named after it.
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Rows is the total number of rows, which are split evenly across Files files.
+	Rows  int   `json:"rows"`
+	Files int   `json:"files"`
+	Seed  int64 `json:"seed"`
+
+	Columns []columnSpec `json:"columns"`
+}
+
+type columnSpec struct {
+	Name string `json:"name"`
+	// Type is one of the property types: string, integer, number, datetime or boolean.
+	Type string `json:"type"`
+	// NullRate is the fraction of values which are empty.
+	NullRate float64 `json:"nullRate"`
+	// GarbageRate is the fraction of values which can't be parsed as Type,
+	// making their rows invalid. It doesn't apply to strings.
+	GarbageRate float64 `json:"garbageRate"`
+	// Unique columns have a different value in every row, and are never null or garbage.
+	// Every spec needs a unique integer or string column, so rows can be identified.
+	Unique bool `json:"unique"`
+	// DateFormats are the Go time layouts datetime values are written in,
+	// picked at random for each value. The default is RFC 3339.
+	DateFormats []string `json:"dateFormats"`
+	// Unicode strings include non-ASCII characters, quotes and commas.
+	Unicode bool `json:"unicode"`
+}
+
+var propertyTypes = []string{"string", "integer", "number", "datetime", "boolean"}
+
+// garbageValues can't be parsed as the type they're written under.
+var garbageValues = map[string][]string{
+	"integer":  {"seventeen", "12abc", "#REF!", "n/a"},
+	"number":   {"one point five", "1.2.3", "#VALUE!", "--"},
+	"boolean":  {"maybe", "sometimes", "tru", "nope"},
+	"datetime": {"not a date", "2019-13-45", "yesterday", "31/31/2019"},
+}
+
+var unicodeStrings = []string{"Zoë", "Ærøskøbing", "東京", "Ελλάδα", "naïve café", "😀 party", "Smith, John", `say "hi"`, "Straße"}
+
+// runGenerate parses the arguments to the generate command and generates the data set.
+// Unlike the other commands it doesn't need a plugin.
+func runGenerate(args []string) error {
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	specPath := fs.String("spec", "", "JSON file describing the data set to generate")
+	out := fs.String("out", "generated", "directory to write the files and the ground truth manifest to")
+	fs.Parse(args)
+
+	if *specPath == "" {
+		fs.Usage()
+		return errors.New("expected -spec")
+	}
+
+	b, err := ioutil.ReadFile(*specPath)
+	if err != nil {
+		return err
+	}
+	spec := new(datasetSpec)
+	if err = json.Unmarshal(b, spec); err != nil {
+		return errors.Wrapf(err, "bad spec in %s", *specPath)
+	}
+	if err = spec.validate(); err != nil {
+		return errors.WithMessage(err, "bad spec in "+*specPath)
+	}
+
+	if err = os.MkdirAll(*out, 0755); err != nil {
+		return err
+	}
+	m, err := generateDataset(spec, *out)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(*out, spec.Name+manifestSuffix)
+	if err = m.save(path); err != nil {
+		return err
+	}
+
+	log.Printf("generated %d rows (%d invalid) in %d files for schema %s", m.Rows, len(m.Invalid), len(m.Files), m.Name)
+	log.Printf("wrote ground truth to %s; run it with -manifest %s", path, path)
+	return nil
+}
+
+func (s *datasetSpec) validate() error {
+	switch {
+	case s.Name == "":
+		return errors.New("name is required")
+	case s.Rows < 1:
+		return errors.New("rows must be at least 1")
+	case len(s.Columns) == 0:
+		return errors.New("columns are required")
+	}
+	if s.Files < 1 {
+		s.Files = 1
+	}
+	if s.Seed == 0 {
+		s.Seed = 1
+	}
+
+	key := -1
+	for i, c := range s.Columns {
+		if !isPropertyType(c.Type) {
+			return errors.Errorf("column %q has type %q, which isn't one of %v", c.Name, c.Type, propertyTypes)
+		}
+		if c.NullRate < 0 || c.GarbageRate < 0 || c.NullRate+c.GarbageRate > 1 {
+			return errors.Errorf("column %q has rates which aren't fractions", c.Name)
+		}
+		if c.Type == "string" && c.GarbageRate > 0 {
+			return errors.Errorf("column %q is a string, so it can't have garbage", c.Name)
+		}
+		if c.Unique {
+			if c.Type != "integer" && c.Type != "string" {
+				return errors.Errorf("column %q can't be unique because it isn't an integer or a string", c.Name)
+			}
+			if c.NullRate > 0 || c.GarbageRate > 0 {
+				return errors.Errorf("column %q can't be unique and have nulls or garbage", c.Name)
+			}
+			if key < 0 {
+				key = i
+			}
+		}
+	}
+	if key < 0 {
+		return errors.New("a unique integer or string column is required, so rows can be identified")
+	}
+	return nil
+}
+
+func isPropertyType(t string) bool {
+	for _, p := range propertyTypes {
+		if t == p {
+			return true
+		}
+	}
+	return false
+}
+
+// generateDataset writes the files for spec into dir and returns their ground truth.
+func generateDataset(spec *datasetSpec, dir string) (*manifest, error) {
+	r := rand.New(rand.NewSource(spec.Seed))
+	m := &manifest{
+		Name:        spec.Name,
+		Description: spec.Description,
+		Glob:        spec.Name + ".csv",
+		Rows:        spec.Rows,
+		Key:         -1,
+	}
+	header := make([]string, len(spec.Columns))
+	for i, c := range spec.Columns {
+		header[i] = c.Name
+		m.Properties = append(m.Properties, &manifestProperty{Name: c.Name, Type: c.Type})
+		if c.Unique && m.Key < 0 {
+			m.Key = i
+		}
+	}
+	if spec.Files > 1 {
+		m.Glob = spec.Name + ".*.csv"
+	}
+
+	row := 0
+	for f := 1; f <= spec.Files; f++ {
+		name := spec.Name + ".csv"
+		if spec.Files > 1 {
+			name = fmt.Sprintf("%s.%d.csv", spec.Name, f)
+		}
+		// Spread the remainder over the first files.
+		rows := spec.Rows / spec.Files
+		if f <= spec.Rows%spec.Files {
+			rows++
+		}
+
+		file, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		w := csv.NewWriter(file)
+		w.Write(header)
+		for line := 2; line < rows+2; line++ {
+			row++
+			values := make([]string, len(spec.Columns))
+			var invalid *invalidRow
+			for i, c := range spec.Columns {
+				p := m.Properties[i]
+				x := r.Float64()
+				switch {
+				case c.Unique:
+					values[i] = uniqueValue(c, row)
+				case x < c.NullRate:
+					p.Nulls++
+				case x < c.NullRate+c.GarbageRate:
+					garbage := garbageValues[c.Type]
+					values[i] = garbage[r.Intn(len(garbage))]
+					p.Invalid++
+					if invalid == nil {
+						invalid = &invalidRow{File: name, Line: line}
+					}
+					invalid.Properties = append(invalid.Properties, i)
+					invalid.Values = append(invalid.Values, values[i])
+				default:
+					values[i] = randomValue(r, c)
+				}
+			}
+			if invalid != nil {
+				invalid.Key = keyValue(spec.Columns[m.Key], values[m.Key])
+				m.Invalid = append(m.Invalid, invalid)
+			}
+			w.Write(values)
+		}
+		w.Flush()
+		if err = w.Error(); err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err = file.Close(); err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, &manifestFile{Name: name, Rows: rows})
+	}
+
+	return m, nil
+}
+
+func uniqueValue(c columnSpec, row int) string {
+	if c.Type == "integer" {
+		return strconv.Itoa(row)
+	}
+	return fmt.Sprintf("%s-%d", c.Name, row)
+}
+
+// keyValue returns value the way it appears in published data,
+// so records can be matched with the ground truth.
+func keyValue(c columnSpec, value string) interface{} {
+	if c.Type == "integer" {
+		n, _ := strconv.Atoi(value)
+		return float64(n)
+	}
+	return value
+}
+
+func randomValue(r *rand.Rand, c columnSpec) string {
+	switch c.Type {
+	case "integer":
+		return strconv.Itoa(r.Intn(2000000) - 1000000)
+	case "number":
+		return strconv.FormatFloat(r.Float64()*2000-1000, 'f', 2, 64)
+	case "boolean":
+		return strconv.FormatBool(r.Intn(2) == 0)
+	case "datetime":
+		t := time.Unix(r.Int63n(1900000000), 0).UTC()
+		layout := time.RFC3339
+		if len(c.DateFormats) > 0 {
+			layout = c.DateFormats[r.Intn(len(c.DateFormats))]
+		}
+		return t.Format(layout)
+	default:
+		if c.Unicode && r.Intn(2) == 0 {
+			return unicodeStrings[r.Intn(len(unicodeStrings))]
+		}
+		return randomName(r)
+	}
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	goldenDir    string
+	updateGolden bool
+)
+
+// goldenDiffLimit is the number of changed records of each kind listed for a test.
+const goldenDiffLimit = 10
+
+func init() {
+	flag.StringVar(&goldenDir, "golden", "testdata/golden", "directory of golden files with the expected discover and publish output of tests")
+	flag.BoolVar(&updateGolden, "update-golden", false, "write the discover and publish output of each test to its golden file, instead of comparing it")
+}
+
+// golden is the normalized output of a test: the schemas the plugin
+// discovered, and every record it published, sorted.
+type golden struct {
+	Schemas []*goldenSchema `json:"schemas"`
+	// Records are compact JSON, so they can be compared as strings.
+	Records []string `json:"-"`
+}
+
+// goldenSchema leaves out the settings and statistics of a discovered schema,
+// which can change from run to run.
+type goldenSchema struct {
+	Name       string            `json:"name"`
+	Properties []*goldenProperty `json:"properties"`
+}
+
+type goldenProperty struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	IsKey bool   `json:"isKey,omitempty"`
+}
+
+func (p *goldenProperty) String() string {
+	s := p.Name + " (" + p.Type
+	if p.IsKey {
+		s += ", key"
+	}
+	return s + ")"
+}
+
+type goldenRecord struct {
+	Data    interface{}           `json:"data"`
+	Invalid bool                  `json:"invalid,omitempty"`
+	Error   string                `json:"error,omitempty"`
+	Errors  []*plugin.RecordError `json:"errors,omitempty"`
+}
+
+// snapshot collects the output of a test to compare with, or write to, its golden file.
+type snapshot struct {
+	path    string
+	current *golden
+}
+
+// newSnapshot starts a snapshot of the output of the named test, after discovery.
+// It returns nil if the test has no golden file and -update-golden isn't set.
+func newSnapshot(name string, discover *plugin.DiscoverResponse) *snapshot {
+	path := filepath.Join(goldenDir, goldenName(name)+".json")
+	if !updateGolden {
+		if _, err := os.Stat(path); err != nil {
+			return nil
+		}
+	}
+
+	g := new(golden)
+	for _, s := range discover.Schemas {
+		gs := &goldenSchema{Name: s.Name}
+		for _, p := range s.Properties {
+			gs.Properties = append(gs.Properties, &goldenProperty{Name: p.Name, Type: p.Type, IsKey: p.IsKey})
+		}
+		g.Schemas = append(g.Schemas, gs)
+	}
+	sort.Slice(g.Schemas, func(i, j int) bool {
+		return g.Schemas[i].Name < g.Schemas[j].Name
+	})
+	return &snapshot{path: path, current: g}
+}
+
+// add records a published record, as the plugin sent it.
+func (s *snapshot) add(record *plugin.PublishRecord) {
+	s.current.Records = append(s.current.Records, normalizeRecord(record))
+}
+
+// normalizeRecord returns record as compact JSON, without progress.
+func normalizeRecord(record *plugin.PublishRecord) string {
+	r := goldenRecord{
+		Invalid: record.Invalid,
+		Error:   record.Error,
+		Errors:  record.Errors,
+	}
+	// Re-encoding the data makes the formatting of numbers consistent.
+	if err := json.Unmarshal([]byte(record.Data), &r.Data); err != nil {
+		r.Data = record.Data
+	}
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+// check writes the snapshot to the golden file with -update-golden, and
+// otherwise compares it with the golden file, commenting on the differences.
+func (s *snapshot) check(result *testResult) error {
+	sort.Strings(s.current.Records)
+
+	if updateGolden {
+		if err := s.current.save(s.path); err != nil {
+			return errors.WithMessage(err, "couldn't update golden file")
+		}
+		result.comment("%s", color.GreenString("updated golden file %s with %d schemas and %d records", s.path, len(s.current.Schemas), len(s.current.Records)))
+		return nil
+	}
+
+	want, err := loadGolden(s.path)
+	if err != nil {
+		return err
+	}
+	schemaDiffs := diffSchemas(want.Schemas, s.current.Schemas)
+	removed, added := diffRecords(want.Records, s.current.Records)
+	if len(schemaDiffs) == 0 && len(removed) == 0 && len(added) == 0 {
+		result.comment("%s", color.GreenString("output matches golden file %s", s.path))
+		return nil
+	}
+
+	for _, d := range schemaDiffs {
+		result.comment("%s", d)
+	}
+	listRecords(result, removed, "-", color.RedString)
+	listRecords(result, added, "+", color.GreenString)
+	return errors.Errorf("output differs from golden file %s: %d schema changes, %d records missing, %d records new (run with -update-golden to accept)",
+		s.path, len(schemaDiffs), len(removed), len(added))
+}
+
+func listRecords(result *testResult, records []string, prefix string, paint func(string, ...interface{}) string) {
+	for i, r := range records {
+		if i == goldenDiffLimit {
+			result.comment("  ...and %d more", len(records)-i)
+			break
+		}
+		result.comment("%s", paint("%s %s", prefix, r))
+	}
+}
+
+// diffSchemas describes the differences between the golden and discovered schemas.
+func diffSchemas(want, got []*goldenSchema) []string {
+	byName := map[string]*goldenSchema{}
+	for _, s := range got {
+		byName[s.Name] = s
+	}
+	var diffs []string
+	for _, w := range want {
+		g, ok := byName[w.Name]
+		if !ok {
+			diffs = append(diffs, color.RedString("- schema %s was not discovered", w.Name))
+			continue
+		}
+		delete(byName, w.Name)
+		for i := 0; i < len(w.Properties) || i < len(g.Properties); i++ {
+			switch {
+			case i >= len(g.Properties):
+				diffs = append(diffs, color.RedString("- schema %s property %d %s", w.Name, i, w.Properties[i]))
+			case i >= len(w.Properties):
+				diffs = append(diffs, color.GreenString("+ schema %s property %d %s", w.Name, i, g.Properties[i]))
+			case *w.Properties[i] != *g.Properties[i]:
+				diffs = append(diffs, color.YellowString("~ schema %s property %d was %s, now %s", w.Name, i, w.Properties[i], g.Properties[i]))
+			}
+		}
+	}
+	for _, g := range got {
+		if _, ok := byName[g.Name]; ok {
+			diffs = append(diffs, color.GreenString("+ schema %s was discovered", g.Name))
+		}
+	}
+	return diffs
+}
+
+// diffRecords returns the records only in want and the records only in got,
+// which must both be sorted.
+func diffRecords(want, got []string) (removed, added []string) {
+	i, j := 0, 0
+	for i < len(want) && j < len(got) {
+		switch {
+		case want[i] == got[j]:
+			i++
+			j++
+		case want[i] < got[j]:
+			removed = append(removed, want[i])
+			i++
+		default:
+			added = append(added, got[j])
+			j++
+		}
+	}
+	removed = append(removed, want[i:]...)
+	added = append(added, got[j:]...)
+	return removed, added
+}
+
+// save writes g with one record per line, so changes to golden files are easy to review.
+func (g *golden) save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	schemas, err := json.MarshalIndent(g.Schemas, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "{\n  \"schemas\": %s,\n  \"records\": [", schemas)
+	for i, r := range g.Records {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    " + r)
+	}
+	buf.WriteString("\n  ]\n}\n")
+	return ioutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func loadGolden(path string) (*golden, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Schemas []*goldenSchema   `json:"schemas"`
+		Records []json.RawMessage `json:"records"`
+	}
+	if err = json.Unmarshal(b, &file); err != nil {
+		return nil, errors.Wrapf(err, "bad golden file %s", path)
+	}
+
+	g := &golden{Schemas: file.Schemas}
+	for _, r := range file.Records {
+		compact := new(bytes.Buffer)
+		json.Compact(compact, r)
+		g.Records = append(g.Records, compact.String())
+	}
+	// Sort in case the file was edited by hand.
+	sort.Strings(g.Records)
+	return g, nil
+}
+
+// goldenName replaces characters which can't be in file names.
+func goldenName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/csv"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"github.com/fatih/color"
 	"github.com/naveego/code-challenge-plugin/plugin"
@@ -18,69 +20,200 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
-var pluginStartupTimeout = 5 * time.Second
 var log *golog.Logger
 var flog *golog.Logger
 
+// pluginArgs is the command used to start the plugin.
+var pluginArgs []string
+
+// activePlugin is the running plugin, for tests which
+// measure or control what the plugin process is doing.
+var activePlugin *pluginProcess
+
 func init() {
 	log = golog.New(os.Stdout, color.BlueString("HOST  |"), golog.Ltime|golog.Lmicroseconds)
 	file, _ := os.OpenFile(".log", os.O_TRUNC|os.O_CREATE|os.O_RDWR, 0660)
 	flog = golog.New(file, "", 0)
 }
 
+// command is something the host does with a running plugin.
+type command func(client plugin.PluginClient) error
+
 func main() {
-	if len(os.Args) < 2 {
+	parseConfig()
+
+	if listTests {
+		printTests(selectTests(allTests()))
+		return
+	}
+
+	args := flag.Args()
+	var run command = runTests
+
+	if len(args) > 0 && args[0] == "generate" {
+		if err := runGenerate(args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(args) > 0 {
+		switch args[0] {
+		case "profile":
+			args, run = parseProfileArgs(args[1:])
+		case "bench":
+			args, run = parseBenchArgs(args[1:])
+		case "diff":
+			args, run = parseDiffArgs(args[1:])
+		}
+	}
+
+	if len(args) < 1 {
+		flag.Usage()
 		log.Fatal("expected at least one argument, the command to start the plugin (and its arguments, if any)")
 	}
 
-	stdoutReader, stdoutWriter := io.Pipe()
-	cmd := exec.Command(os.Args[1], os.Args[2:]...)
+	printConfig()
 
-	cmd.Stderr = os.Stdout
-	cmd.Stdout = stdoutWriter
+	runPlugin(args, run)
+}
+
+// runPlugin starts the plugin using args, waits for it to report its port,
+// then connects to it and executes run.
+func runPlugin(args []string, run command) {
+	pluginArgs = args
 
-	exitCh := make(chan int)
-	portCh := make(chan int)
+	p, err := startPlugin(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	activePlugin = p
 
-	go monitorStdout(stdoutReader, portCh)
+	go handleUserExit()
 
-	if err := cmd.Start(); err != nil {
-		log.Fatalf("couldn't start plugin: %s", err)
+	client, err := connect(p.port)
+	if err != nil {
+		log.Print(err)
+	} else {
+		err = run(client)
 	}
 
-	go monitorExit(cmd, exitCh)
-	go handleUserExit(cmd)
+	// Whatever happened, don't leave the plugin (or anything it started) running.
+	activePlugin.stop()
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// pluginProcess is a running instance of the plugin.
+type pluginProcess struct {
+	cmd  *exec.Cmd
+	port int
+	// exited is closed when the plugin exits, after exitCode is set.
+	exited   chan struct{}
+	exitCode int
+	// outputDone is closed when the plugin's stdout and stderr have been copied.
+	outputDone chan struct{}
+	// expected is set (atomically) when the host deliberately stops the plugin,
+	// so that its exit isn't treated as a crash.
+	expected int32
+}
+
+func (p *pluginProcess) pid() int {
+	return p.cmd.Process.Pid
+}
+
+// startPlugin starts the plugin using args and waits for it to report its port.
+func startPlugin(args []string) (*pluginProcess, error) {
+	// The plugin writes straight to these pipes (rather than through a goroutine
+	// started by exec) so that waiting for the plugin to exit doesn't also wait
+	// for any processes it left behind which still have its output open.
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+
+	cmd.Stderr = stderrWriter
+	cmd.Stdout = stdoutWriter
+	setProcessGroup(cmd)
+
+	p := &pluginProcess{
+		cmd:        cmd,
+		exited:     make(chan struct{}),
+		outputDone: make(chan struct{}),
+	}
+	portCh := make(chan int, 1)
+
+	output := new(sync.WaitGroup)
+	output.Add(2)
+	go func() {
+		monitorStdout(stdoutReader, portCh)
+		output.Done()
+	}()
+	go func() {
+		monitorStderr(stderrReader)
+		output.Done()
+	}()
+	go func() {
+		output.Wait()
+		close(p.outputDone)
+	}()
+
+	err = cmd.Start()
+	stdoutWriter.Close()
+	stderrWriter.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't start plugin")
+	}
+
+	go monitorExit(p)
 
 	select {
 	case <-time.After(pluginStartupTimeout):
-		log.Fatalf("did not get a port from the plugin within timeout of %s", pluginStartupTimeout)
-	case exitCode := <-exitCh:
-		if exitCode != 0 {
-			log.Fatalf("plugin exited with non-zero code %d", exitCode)
-		}
-		os.Exit(exitCode)
-	case port := <-portCh:
-		err := runTests(port)
-		if err != nil {
-			os.Exit(1)
-		}
+		p.expectExit()
+		p.kill()
+		return nil, errors.Errorf("did not get a port from the plugin within timeout of %s", pluginStartupTimeout)
+	case <-p.exited:
+		p.kill()
+		return nil, errors.Errorf("plugin exited with code %d before reporting its port", p.exitCode)
+	case p.port = <-portCh:
+		return p, nil
 	}
 }
 
-func runTests(port int) error {
-	addr := fmt.Sprintf("localhost:%d", port)
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
-	conn, err := grpc.DialContext(ctx, addr, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithReadBufferSize(500))
+func connect(port int) (plugin.PluginClient, error) {
+	conn, err := dial(port)
 	if err != nil {
-		return errors.WithMessage(err, "connection failed")
+		return nil, err
 	}
 
-	client := plugin.NewPluginClient(conn)
+	return plugin.NewPluginClient(conn), nil
+}
 
+// dial connects to the plugin listening on port, for callers which close the connection themselves.
+func dial(port int) (*grpc.ClientConn, error) {
+	addr := fmt.Sprintf("localhost:%d", port)
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, addr, dialOptions()...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "connection failed")
+	}
+	return conn, nil
+}
+
+// allTests returns every test the host knows how to run.
+func allTests() []test {
 	pwd, _ := os.Getwd()
 	tests := []test{
 		&standardTestCase{
@@ -92,7 +225,7 @@ func runTests(port int) error {
 			expectedSchemas: []plugin.Schema{schemaAnimals},
 			recordChecks: expectedRecords{
 				requiredRecordCheck(1, "Vulpes chama"),
-				invalidRecordCheck(1, "Macropus fuliginosus", " because blue is not a valid boolean"),
+				invalidRecordCheck(1, "Macropus fuliginosus", 2, " because blue is not a valid boolean"),
 				parsingRecordCheck(0, float64(52), 0, float64(52), " because id column should be parsed as number"),
 				parsingRecordCheck(0, float64(83), 3, timeFromRFC3339String("1796-07-23T00:00:00.000Z"), ` because "last spotted" column should be parsed as date`),
 			},
@@ -141,26 +274,81 @@ func runTests(port int) error {
 		},
 	}
 
-	var results []*testResult
-	total := len(tests)
-	failCount := 0
+	// The extended tests only run with -extended.
+	if extendedTests {
+		tests = append(tests,
+			&stressTestCase{
+				n:    "stress",
+				d:    "This test checks that the plugin can publish several schemas, and the same schema several times, at the same time.",
+				glob: filepath.Join(pwd, "./data/*.csv"),
+				schemas: []stressSchema{
+					{schema: schemaAnimals, expectedCount: 100},
+					{schema: schemaLogs, expectedCount: 300},
+					{schema: schemaPeople, expectedCount: 3000},
+				},
+				streamsPerSchema: 8,
+			},
+			&backpressureTestCase{
+				n:               "backpressure",
+				d:               "This test checks that the plugin stops reading data when the host can't receive it fast enough, instead of buffering it all in memory.",
+				rows:            500000,
+				recvDelay:       time.Millisecond,
+				duration:        3 * time.Second,
+				maxGrowth:       32 * 1024 * 1024,
+				discoverTimeout: 30 * time.Second,
+			},
+			&cancellationTestCase{
+				n:               "cancellation",
+				d:               "This test checks that the plugin stops publishing when the host cancels a publish, and still works afterwards.",
+				rows:            500000,
+				cancelAfter:     100,
+				grace:           500 * time.Millisecond,
+				window:          time.Second,
+				maxCPU:          0.2,
+				maxRead:         1024 * 1024,
+				discoverTimeout: 30 * time.Second,
+				glob:            filepath.Join(pwd, "./data/animals.csv"),
+				expectedSchema:  schemaAnimals,
+			},
+		)
+	}
+
+	generated, err := manifestTests()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tests = append(tests, generated...)
+
+	suites, err := suiteTests()
+	if err != nil {
+		log.Fatal(err)
+	}
+	tests = append(tests, suites...)
+
+	// The lifecycle test restarts the plugin, so it goes last.
+	return append(tests, &lifecycleTestCase{
+		n:           "lifecycle",
+		d:           "This test checks that the plugin exits promptly and cleanly when it receives SIGINT or SIGTERM during a publish.",
+		glob:        filepath.Join(pwd, "./data/people.*.csv"),
+		schema:      schemaPeople,
+		signals:     []syscall.Signal{syscall.SIGINT, syscall.SIGTERM},
+		exitTimeout: 2 * time.Second,
+	})
+}
 
-	for i, t := range tests {
-		log.Printf("%d/%d: executing test %q", i+1, total, t.name())
-		log.Printf("description: %s", t.description())
-		flog.Println(strings.Repeat("-", 50))
-		flog.Print(t.name())
-		flog.Println(strings.Repeat("-", 50))
+func runTests(client plugin.PluginClient) error {
+	tests := selectTests(allTests())
+	if len(tests) == 0 {
+		log.Print(color.RedString("no tests match -run %q -skip %q", runPattern, skipPattern))
+		return errors.New("no tests")
+	}
 
-		result := t.execute(client)
-		result.test = t
+	results := executeTests(client, tests)
+	failCount := 0
+	for _, result := range results {
 		if result.err != nil {
 			failCount++
-			log.Printf(color.RedString("test %s failed: %s"), t.name(), result.err)
-		} else {
-			log.Printf(color.GreenString("test %s passed"), t.name())
 		}
-		results = append(results, result)
 	}
 
 	color.Blue("RESULTS")
@@ -178,6 +366,10 @@ func runTests(port int) error {
 			fmt.Println("  " + c)
 		}
 	}
+	printInferenceScore(results)
+	printInvalidScores(results)
+	printResourceUsage(results)
+	printRepeatSummary(results)
 
 	if failCount == 0 {
 		good.Println("PASSED")
@@ -198,32 +390,49 @@ func timeFromRFC3339String(t string) time.Time {
 	return parsed
 }
 
-func handleUserExit(cmd *exec.Cmd) {
-	sigCh := make(chan os.Signal)
+func handleUserExit() {
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Kill, os.Interrupt)
 	sig := <-sigCh
 	log.Printf("user exit: %s", sig)
-	cmd.Process.Kill()
+	activePlugin.kill()
+	if comparedPlugin != nil {
+		comparedPlugin.kill()
+	}
 	os.Exit(0)
 }
 
-func monitorExit(cmd *exec.Cmd, exitCh chan int) {
-	err := cmd.Wait()
+// monitorExit waits for the plugin to exit, records its exit code and closes p.exited.
+// If the plugin was killed by a signal the code is 128 plus the signal number,
+// like a shell would report.
+func monitorExit(p *pluginProcess) {
+	defer close(p.exited)
+	err := p.cmd.Wait()
 	log.Printf("plugin exited")
+	if err == nil {
+		return
+	}
 	exitErr, ok := err.(*exec.ExitError)
 	if !ok {
 		log.Fatalf("plugin exited with error: %s", err)
 	}
 
+	p.exitCode = 1
 	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
-		exitCh <- status.ExitStatus()
+		if status.Signaled() {
+			p.exitCode = 128 + int(status.Signal())
+		} else {
+			p.exitCode = status.ExitStatus()
+		}
 	}
-	exitCh <- 0
 }
 
 func monitorStdout(r io.Reader, portCh chan int) {
 	scanner := bufio.NewScanner(r)
-	scanner.Scan()
+	if !scanner.Scan() {
+		// The plugin exited without writing anything.
+		return
+	}
 	port, err := strconv.Atoi(scanner.Text())
 	if err != nil {
 		log.Fatalf("bad port number %q: %s", scanner.Text(), err)
@@ -231,12 +440,8 @@ func monitorStdout(r io.Reader, portCh chan int) {
 	log.Printf("got port: %d", port)
 	portCh <- port
 
-	pluginLog := golog.New(os.Stdout, color.YellowString("PLUGIN|"), golog.Ltime|golog.Lmicroseconds)
-
 	// write the rest of the plugin's output to stdout
-	for scanner.Scan() {
-		pluginLog.Print(scanner.Text())
-	}
+	copyPluginOutput(scanner)
 }
 
 var schemaPeople = plugin.Schema{
@@ -325,7 +530,7 @@ var schemaLogs = plugin.Schema{
 }
 
 type test interface {
-	execute(client plugin.PluginClient) (*testResult)
+	execute(client plugin.PluginClient, result *testResult) *testResult
 	name() string
 	description() string
 }
@@ -338,7 +543,10 @@ type standardTestCase struct {
 	publishSchema   plugin.Schema
 	recordChecks    expectedRecords
 	expectedCount   int
-	comments        []string
+	// truth is the ground truth for generated data sets, used to score invalid records.
+	truth *manifest
+	// assertions are the checks on published records from suite files.
+	assertions []assertion
 }
 
 func (t *standardTestCase) name() string {
@@ -353,6 +561,19 @@ type testResult struct {
 	test     test
 	err      error
 	comments []string
+	// usage is what the plugin used while the test ran, if it could be measured.
+	usage *resourceUsage
+	// inferences are the types the plugin inferred for the properties of the expected schemas.
+	inferences []typeInference
+	// invalid scores the records the plugin marked invalid, for tests with ground truth.
+	invalid *invalidScore
+	// flaky describes how the output changed between runs, with -repeat;
+	// flakyErr is set if any of the changes failed the test.
+	flaky    []string
+	flakyErr bool
+	// flog collects the data processed by the test; it is written
+	// to the .log file when the test completes.
+	flog *golog.Logger
 }
 
 func (t *testResult) withErr(err error) *testResult {
@@ -379,6 +600,7 @@ type recordCheck struct {
 	checkValue      interface{}
 	isBonus         bool
 	shouldBeInvalid bool
+	invalidIndex    int
 	match           *plugin.PublishRecord
 	reason          string
 	parseErr        error
@@ -391,12 +613,13 @@ func requiredRecordCheck(index int, value interface{}) *recordCheck {
 	}
 }
 
-func invalidRecordCheck(index int, value interface{}, reason string) *recordCheck {
+func invalidRecordCheck(index int, value interface{}, invalidIndex int, reason string) *recordCheck {
 	return &recordCheck{
 		matchIndex:      index,
 		matchValue:      value,
 		isBonus:         true,
 		shouldBeInvalid: true,
+		invalidIndex:    invalidIndex,
 		reason:          reason,
 	}
 }
@@ -442,7 +665,7 @@ func (r *recordCheck) evaluateParsing(record *plugin.PublishRecord, data []inter
 		if r.checkValue == nil {
 			return nil
 		} else {
-			return errors.Errorf("expected value at %s to be nil/null", r.checkIndex)
+			return errors.Errorf("expected value at %d to be nil/null", r.checkIndex)
 		}
 	}
 
@@ -465,7 +688,7 @@ func (r *recordCheck) evaluateParsing(record *plugin.PublishRecord, data []inter
 			return errors.Errorf("expected value at %d to equal %v but it was %v", r.checkIndex, expectedValue, actualFloat)
 		}
 	default:
-		if actual != r.matchValue {
+		if actual != r.checkValue {
 			return errors.Errorf("expected value at %d to equal %v (%T) but it was %v (%T)", r.checkIndex, expectedValue, expectedValue, actual, actual)
 		}
 	}
@@ -473,16 +696,54 @@ func (r *recordCheck) evaluateParsing(record *plugin.PublishRecord, data []inter
 	return nil
 }
 
+// evaluateInvalid checks that the matched record reported a structured
+// error for the property we expected to be invalid, and that the invalid
+// value was written as null.
+func (r *recordCheck) evaluateInvalid() (*plugin.RecordError, error) {
+	if len(r.match.Errors) == 0 {
+		return nil, errors.New("it has no structured errors")
+	}
+
+	var recordErr *plugin.RecordError
+	var reported []string
+	for _, e := range r.match.Errors {
+		if int(e.PropertyIndex) == r.invalidIndex {
+			recordErr = e
+		}
+		reported = append(reported, fmt.Sprintf("%d (%q)", e.PropertyIndex, e.PropertyName))
+	}
+	if recordErr == nil {
+		return nil, errors.Errorf("expected an error for property %d, got errors for %s", r.invalidIndex, strings.Join(reported, ", "))
+	}
+
+	var data []interface{}
+	if err := json.Unmarshal([]byte(r.match.Data), &data); err != nil {
+		return nil, errors.Errorf("data is not a JSON array: %s", err)
+	}
+	if r.invalidIndex >= len(data) {
+		return nil, errors.New("record too narrow")
+	}
+	if data[r.invalidIndex] != nil {
+		return nil, errors.Errorf("invalid property %d should be null in data, but it was %v", r.invalidIndex, data[r.invalidIndex])
+	}
+
+	return recordErr, nil
+}
+
 func (r *recordCheck) result() (ok bool, msg string) {
 	if r.match == nil {
 		return false, color.RedString("expected to see a record with value %v at data index %d%s", r.matchValue, r.matchIndex, r.reason)
 	} else {
 		if r.shouldBeInvalid {
-			if r.match.Invalid {
-				return true, color.GreenString("detected invalid record { %s }", r.match)
-			} else {
+			if !r.match.Invalid {
 				return false, color.RedString("record should have been marked invalid%s: { %s }", r.reason, r.match)
 			}
+			recordErr, err := r.evaluateInvalid()
+			if err != nil {
+				return false, color.RedString("record was marked invalid, but %s%s: { %s }", err, r.reason, r.match)
+			}
+			return true, color.GreenString("detected invalid property %q (%s, original value %q) in record { %s }",
+				recordErr.PropertyName, recordErr.Code, recordErr.OriginalValue, r.match)
 		} else if r.isParseCheck {
 			if r.parseErr == nil {
 				return true, color.GreenString("correctly parsed record { %s }", r.match)
@@ -496,133 +757,411 @@ func (r *recordCheck) result() (ok bool, msg string) {
 
 type expectedRecords []*recordCheck
 
-func (r expectedRecords) evaluate(record *plugin.PublishRecord) {
-	var data []interface{}
-	json.Unmarshal([]byte(record.Data), &data)
+func (r expectedRecords) evaluate(record *plugin.PublishRecord, data []interface{}) {
 	for _, expected := range r {
 		expected.evaluate(record, data)
 	}
 }
 
-func (t *standardTestCase) execute(client plugin.PluginClient) *testResult {
-	result := &testResult{
-		test: t,
+func (t *standardTestCase) execute(client plugin.PluginClient, result *testResult) *testResult {
+	if repeatRuns > 1 {
+		return t.executeRepeats(client, result)
 	}
+	return t.executeRun(client, result, nil)
+}
+
+// executeRun runs the test once. With -repeat, out collects the output of the run.
+func (t *standardTestCase) executeRun(client plugin.PluginClient, result *testResult, out *runOutput) *testResult {
 	result.log("executing discover...")
 
 	settings := &plugin.Settings{
 		FileGlob: t.glob,
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 1*time.Second)
-	discover, err := client.Discover(ctx, &plugin.DiscoverRequest{
-		Settings: settings,
-	})
+	discover, err := discoverSchemas(client, settings, discoverTimeout, result.log)
 	if err != nil {
 		return result.withErr(errors.WithMessage(err, "discovery failed"))
 	}
+	if out != nil {
+		out.discover = discover
+	}
 	result.log("discover completed: %s", discover)
 	result.log("scoring discover...")
 
 	j, _ := json.MarshalIndent(discover, "", "  ")
-	flog.Println("discover response:")
-	flog.Println(string(j))
+	result.flog.Println("discover response:")
+	result.flog.Println(string(j))
 
 	for _, want := range t.expectedSchemas {
-		namesMatch, typesMatch, got := checkSchemaIn(want, discover.Schemas)
-		if !namesMatch {
-			return result.withErr(errors.Errorf("no schema matclng %q was discovered (want: %s, got: %s)", want.Name, want, discover.Schemas))
+		alignment := findAlignment(want, discover.Schemas)
+		if alignment == nil {
+			return result.withErr(errors.Errorf("no schema matching %q was discovered (want: %s, got: %s)", want.Name, want.String(), discover.Schemas))
+		}
+		// Types are scored even when properties are missing, so do it before failing.
+		recordInferences(result, alignment)
+		err := alignment.report(result)
+		if alignment.typesMatch() {
+			result.comment("%s", color.GreenString("inferred types on schema %s: ", want.Name)+want.String())
+		} else if mismatches := alignment.typeMismatches(); mismatches != "" {
+			result.comment("%s", color.RedString("did not infer types on schema %s: ", want.Name)+mismatches)
+		}
+		if err != nil {
+			return result.withErr(err)
 		}
-		if typesMatch {
-			result.comment(color.GreenString("inferred types on schema %s: ", want.Name) + want.String())
-		} else {
-
-			w := new(strings.Builder)
-			for i := range got.Properties {
-				wp := want.Properties[i]
-				gp := got.Properties[i]
-				if wp.Type != gp.Type {
-					fmt.Fprintf(w, "%s: wanted %s, got %s; ", wp.Name, color.GreenString(wp.Type), color.RedString(gp.Type))
-				}
-			}
 
-			result.comment(color.RedString("did not infer types on schema %s: ", want.Name) + w.String())
+		if err := t.checkKeys(result, alignment); err != nil {
+			return result.withErr(err)
 		}
 	}
 	result.log("discover looks correct")
 	result.log("executing publish...")
-	flog.Println()
+	result.flog.Println()
 
-	targetSchema := findSchemaIn(t.publishSchema, discover.Schemas)
+	target := findAlignment(t.publishSchema, discover.Schemas)
+	if target == nil {
+		return result.withErr(errors.Errorf("no schema matching %q was discovered", t.publishSchema.Name))
+	}
+	targetSchema := target.got
 
-	ctx, _ = context.WithTimeout(context.Background(), 2*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
 	stream, err := client.Publish(ctx, &plugin.PublishRequest{
 		Settings: settings,
 		Schema:   targetSchema,
+		Order:    plugin.RecordOrder(publishOrder),
 	})
 	if err != nil {
 		return result.withErr(errors.Wrap(err, "publish failed"))
 	}
 
+	var order *orderCheck
+	if plugin.RecordOrder(publishOrder) == plugin.RecordOrder_RECORD_ORDER_FILE {
+		if order, err = newOrderCheck(t.glob, t.publishSchema); err != nil {
+			return result.withErr(errors.WithMessage(err, "couldn't find the file order of the records"))
+		}
+	}
+
+	// Later runs of -repeat are compared with the first, not the golden file.
+	var snap *snapshot
+	if out == nil || out.run == 1 {
+		snap = newSnapshot(t.name(), discover)
+	}
+
+	var invalid *invalidScore
+	if t.truth != nil {
+		invalid = newInvalidScore(t.truth)
+	}
+
 	var count = 0
+	progress := newProgressBar(t.name())
 	for {
 		record, err := stream.Recv()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
+			progress.finish(count)
 			return result.withErr(errors.Errorf("publish error on record %d: %s", count, err))
 		}
+		if record.Progress != nil {
+			progress.update(record.Progress, count)
+			continue
+		}
 		count++
 		j, _ = json.MarshalIndent(record, "", "  ")
-		flog.Println(string(j))
-		t.recordChecks.evaluate(record)
+		result.flog.Println(string(j))
+		if snap != nil {
+			snap.add(record)
+		}
+		if out != nil {
+			out.records = append(out.records, normalizeRecord(record))
+		}
+		record = target.record(record)
+		var data []interface{}
+		json.Unmarshal([]byte(record.Data), &data)
+		t.recordChecks.evaluate(record, data)
+		for _, a := range t.assertions {
+			a.evaluate(record, data)
+		}
+		if invalid != nil {
+			invalid.add(record)
+		}
+		if order != nil {
+			order.evaluate(record, data)
+		}
+	}
+	progress.finish(count)
+	if out != nil {
+		out.published = true
 	}
 	result.log("publish completed, analyzing data...")
 
+	if invalid != nil {
+		invalid.finish()
+		invalid.report(result)
+		result.invalid = invalid
+	}
+
+	if snap != nil {
+		if err := snap.check(result); err != nil {
+			return result.withErr(err)
+		}
+	}
+
 	if count != t.expectedCount {
 		return result.withErr(errors.Errorf("publish did not return the right number of records (wanted %d, got %d)", t.expectedCount, count))
 	}
 
 	result.log("publish has correct count, %d", count)
 
+	if order != nil {
+		if err := order.report(result); err != nil {
+			return result.withErr(err)
+		}
+	}
+
 	for _, e := range t.recordChecks {
 		ok, msg := e.result()
+		if msg == "" {
+			// Required checks which pass have nothing to say.
+			continue
+		}
 		if ok {
-			result.comment(msg)
+			result.comment("%s", msg)
 		} else {
 			if e.isBonus {
-				result.comment(msg)
+				result.comment("%s", msg)
 			} else {
 				return result.withErr(errors.Errorf("record check failed: %s", msg))
 			}
 		}
 	}
+
+	failed := 0
+	for _, a := range t.assertions {
+		if err := a.result(); err != nil {
+			failed++
+			result.comment("%s", color.RedString("assertion failed: %s: %s", a, err))
+		} else {
+			result.comment("%s", color.GreenString("assertion held: %s", a))
+		}
+	}
+	if failed > 0 {
+		return result.withErr(errors.Errorf("%d of %d assertions failed", failed, len(t.assertions)))
+	}
 	result.log("published data looks correct")
 
 	return result
 }
 
-var mismatch = color.New(color.CrossedOut, color.FgRed)
+// noKeysOnce reports a plugin which marks no keys on a schema with candidate keys, once.
+var noKeysOnce sync.Once
 
-func checkSchemaIn(want plugin.Schema, in []*plugin.Schema) (namesMatch bool, typesMatch bool, found *plugin.Schema) {
+// checkKeys compares the properties the plugin marked as keys on the discovered
+// schema with the columns which are actually unique and non-null across all
+// the files for the schema. Marking a property as a key when it isn't one is an error.
+func (t *standardTestCase) checkKeys(result *testResult, alignment *schemaAlignment) error {
+	want, got := alignment.want, alignment.got
+	files, err := filesForSchema(t.glob, want)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't find files for schema "+want.Name)
+	}
+	_, keys, err := detectKeys(files)
+	if err != nil {
+		return errors.WithMessage(err, "couldn't detect keys for schema "+want.Name)
+	}
+
+	isKey := map[int]bool{}
+	for _, i := range keys {
+		isKey[i] = true
+	}
 
-	for _, found := range in {
-		if namesMatch, typesMatch = checkSchema(want, found); namesMatch {
-			return namesMatch, typesMatch, found
+	var reported, missed []string
+	for j, p := range got.Properties {
+		// The keys are indexes into the file's columns, which are in the order of the expected properties.
+		i := alignment.wantIndex[j]
+		if i < 0 {
+			continue
+		}
+		switch {
+		case p.IsKey && !isKey[i]:
+			return errors.Errorf("property %q on schema %s was marked as a key, but its values are not unique and non-null across %d file(s)", p.Name, want.Name, len(files))
+		case p.IsKey:
+			reported = append(reported, p.Name)
+		case isKey[i]:
+			missed = append(missed, p.Name)
 		}
 	}
 
-	return false, false, nil
+	if len(reported) > 0 {
+		result.comment("%s", color.GreenString("detected keys on schema %s: %s", want.Name, strings.Join(reported, ", ")))
+	}
+	switch {
+	case len(missed) == 0:
+	case len(reported) > 0:
+		result.comment("%s", color.YellowString("did not mark candidate keys on schema %s: %s", want.Name, strings.Join(missed, ", ")))
+	default:
+		// A plugin which doesn't mark keys at all would otherwise get this in every test.
+		noKeysOnce.Do(func() {
+			result.comment("%s", color.YellowString("did not mark any keys on schema %s (candidates: %s); this is only reported once", want.Name, strings.Join(missed, ", ")))
+		})
+	}
+	return nil
 }
 
-func findSchemaIn(want plugin.Schema, in []*plugin.Schema) (found *plugin.Schema) {
+// filesForSchema returns the files matching glob which have the properties of want as their header.
+func filesForSchema(glob string, want plugin.Schema) ([]string, error) {
+	var names []string
+	for _, p := range want.Properties {
+		names = append(names, normalizeName(p.Name))
+	}
+	header := strings.Join(names, ",")
+
+	matches, err := filepath.Glob(glob)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, file := range matches {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		line, err := bufio.NewReader(f).ReadString('\n')
+		f.Close()
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		var columns []string
+		for _, c := range strings.Split(strings.TrimRight(line, "\r\n"), ",") {
+			columns = append(columns, normalizeName(c))
+		}
+		if strings.Join(columns, ",") == header {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// keyDetector finds the columns of a schema which could be used as keys,
+// meaning that every row has a value in the column and no value repeats.
+// Rows from all the files of a schema should be added to the same keyDetector,
+// since a column which is unique within each file may not be unique across them.
+type keyDetector struct {
+	seen      []map[string]bool
+	candidate []bool
+	rows      int
+}
+
+func newKeyDetector(width int) *keyDetector {
+	d := &keyDetector{
+		seen:      make([]map[string]bool, width),
+		candidate: make([]bool, width),
+	}
+	for i := range d.seen {
+		d.seen[i] = map[string]bool{}
+		d.candidate[i] = true
+	}
+	return d
+}
+
+// add records the values in a single row. Missing and empty values
+// disqualify their column from being a key.
+func (d *keyDetector) add(row []string) {
+	d.rows++
+	for i := range d.candidate {
+		if !d.candidate[i] {
+			continue
+		}
+		if i >= len(row) {
+			d.candidate[i] = false
+			continue
+		}
+		value := strings.TrimSpace(row[i])
+		if value == "" || d.seen[i][value] {
+			d.candidate[i] = false
+			d.seen[i] = nil
+			continue
+		}
+		d.seen[i][value] = true
+	}
+}
 
-	for _, found = range in {
-		if namesMatch, _ := checkSchema(want, found); namesMatch {
-			return
+// keys returns the indexes of the columns which are unique and non-null
+// in every row added so far. If no rows have been added there are no keys.
+func (d *keyDetector) keys() []int {
+	var keys []int
+	if d.rows == 0 {
+		return keys
+	}
+	for i, ok := range d.candidate {
+		if ok {
+			keys = append(keys, i)
+		}
+	}
+	return keys
+}
+
+// detectKeys reads the CSV files (which must all have the same header)
+// and returns the header and the indexes of the columns which could be keys.
+func detectKeys(files []string) (header []string, keys []int, err error) {
+	var d *keyDetector
+	for _, file := range files {
+		err = func() error {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			r := csv.NewReader(f)
+			r.FieldsPerRecord = -1
+			r.LazyQuotes = true
+
+			h, err := r.Read()
+			if err != nil {
+				return errors.Wrap(err, "couldn't read header")
+			}
+			if d == nil {
+				header = h
+				d = newKeyDetector(len(h))
+			} else if strings.Join(h, ",") != strings.Join(header, ",") {
+				return errors.Errorf("header %v does not match header %v", h, header)
+			}
+
+			for {
+				row, err := r.Read()
+				if err == io.EOF {
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				d.add(row)
+			}
+		}()
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, file)
 		}
 	}
+	if d == nil {
+		return nil, nil, nil
+	}
+	return header, d.keys(), nil
+}
+
+func checkSchemaIn(want plugin.Schema, in []*plugin.Schema) (namesMatch bool, typesMatch bool, found *plugin.Schema) {
+	alignment := findAlignment(want, in)
+	if alignment == nil || !alignment.complete() {
+		return false, false, nil
+	}
+	return true, alignment.typesMatch(), alignment.got
+}
 
+func findSchemaIn(want plugin.Schema, in []*plugin.Schema) (found *plugin.Schema) {
+	if alignment := findAlignment(want, in); alignment != nil && alignment.complete() {
+		return alignment.got
+	}
 	return nil
 }
 
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestKeyDetector(t *testing.T) {
+	tests := []struct {
+		rows [][]string
+		keys []int
+	}{
+		{nil, nil},
+		{[][]string{{"1", "a", "x"}}, []int{0, 1, 2}},
+		{[][]string{{"1", "a", "x"}, {"2", "b", "x"}}, []int{0, 1}},
+		// Empty and missing values can't be keys, and surrounding whitespace doesn't make values distinct.
+		{[][]string{{"1", "a", "x"}, {"2", "", "y"}}, []int{0, 2}},
+		{[][]string{{"1", "a", "x"}, {"2", "b"}}, []int{0, 1}},
+		{[][]string{{"1", "a", "x"}, {"2", "b", " x "}}, []int{0, 1}},
+		{[][]string{{"1", "a", "x"}, {"2", "b", "y"}, {"1", "c", "z"}}, []int{1, 2}},
+	}
+	for _, test := range tests {
+		d := newKeyDetector(3)
+		for _, row := range test.rows {
+			d.add(row)
+		}
+		if keys := d.keys(); fmt.Sprint(keys) != fmt.Sprint(test.keys) {
+			t.Errorf("keys of %v = %v, want %v", test.rows, keys, test.keys)
+		}
+	}
+}
+
+func TestDetectKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	write := func(name string, lines ...string) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	a := write("a.csv", "id,name,city", "1,Ann,Oslo", "2,Bob,Rome")
+	b := write("b.csv", "id,name,city", "3,Cy,Oslo", `4,"Lee, Dee",Lima`)
+	c := write("c.csv", "id,name", "5,Eve")
+
+	header, keys, err := detectKeys([]string{a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(header, ",") != "id,name,city" || fmt.Sprint(keys) != "[0 1 2]" {
+		t.Errorf("a.csv: header %v, keys %v", header, keys)
+	}
+
+	// A column which is unique in each file isn't a key if values repeat across them.
+	_, keys, err = detectKeys([]string{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(keys) != "[0 1]" {
+		t.Errorf("a.csv and b.csv: keys %v, want [0 1]", keys)
+	}
+
+	if _, _, err = detectKeys([]string{a, c}); err == nil || !strings.Contains(err.Error(), "does not match header") {
+		t.Errorf("files with different headers: got error %v", err)
+	}
+}
+
+func TestPeopleKeys(t *testing.T) {
+	files, err := filesForSchema("data/people.*.csv", schemaPeople)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("files for people = %v, want people.1.csv to people.3.csv", files)
+	}
+
+	keyNames := func(files []string) string {
+		header, keys, err := detectKeys(files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, i := range keys {
+			names = append(names, header[i])
+		}
+		return strings.Join(names, ",")
+	}
+	// Every file numbers its rows from 1, so id is only a key within each file.
+	if names := keyNames(files[:1]); names != "id,email,ip_address" {
+		t.Errorf("keys of %s = %s, want id,email,ip_address", files[0], names)
+	}
+	if names := keyNames(files); names != "email,ip_address" {
+		t.Errorf("keys of people.1.csv to people.3.csv = %s, want email,ip_address", names)
+	}
+
+	test := &standardTestCase{glob: "data/people.*.csv"}
+	got := &plugin.Schema{Name: "people"}
+	for _, p := range schemaPeople.Properties {
+		isKey := p.Name == "id" || p.Name == "email"
+		got.Properties = append(got.Properties, &plugin.Property{Name: p.Name, Type: p.Type, IsKey: isKey})
+	}
+	err = test.checkKeys(&testResult{}, alignSchema(schemaPeople, got))
+	if err == nil || !strings.Contains(err.Error(), `property "id"`) {
+		t.Errorf("checkKeys with id marked as a key: got error %v", err)
+	}
+	got.Properties[0].IsKey = false
+	if err = test.checkKeys(&testResult{}, alignSchema(schemaPeople, got)); err != nil {
+		t.Errorf("checkKeys with email marked as a key: %s", err)
+	}
+}
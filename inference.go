@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// noType labels properties the plugin didn't give a type.
+const noType = "(none)"
+
+// typeInference is the type a plugin inferred for a property, and the type it should have inferred.
+type typeInference struct {
+	schema   string
+	property string
+	want     string
+	got      string
+}
+
+// recordInferences records the types the plugin inferred for the
+// discovered properties of alignment against result.
+func recordInferences(result *testResult, alignment *schemaAlignment) {
+	for i, wp := range alignment.want.Properties {
+		j := alignment.gotIndex[i]
+		if j < 0 {
+			continue
+		}
+		gotType := alignment.got.Properties[j].Type
+		if gotType == "" {
+			gotType = noType
+		}
+		result.inferences = append(result.inferences, typeInference{
+			schema:   alignment.want.Name,
+			property: wp.Name,
+			want:     wp.Type,
+			got:      gotType,
+		})
+	}
+}
+
+// inferenceScore summarizes how well the plugin inferred types across all the tests.
+type inferenceScore struct {
+	correct int
+	total   int
+	// properties are the schema.property names in the order they were first
+	// seen, and byProperty has the score for each of them.
+	properties []string
+	byProperty map[string]*propertyScore
+	// confusion counts inferences by wanted type, then by inferred type.
+	confusion map[string]map[string]int
+}
+
+type propertyScore struct {
+	want    string
+	got     map[string]int
+	correct int
+	total   int
+}
+
+func scoreInferences(results []*testResult) *inferenceScore {
+	s := &inferenceScore{
+		byProperty: map[string]*propertyScore{},
+		confusion:  map[string]map[string]int{},
+	}
+	for _, result := range results {
+		for _, inf := range result.inferences {
+			key := inf.schema + "." + inf.property
+			p, ok := s.byProperty[key]
+			if !ok {
+				p = &propertyScore{want: inf.want, got: map[string]int{}}
+				s.byProperty[key] = p
+				s.properties = append(s.properties, key)
+			}
+			if s.confusion[inf.want] == nil {
+				s.confusion[inf.want] = map[string]int{}
+			}
+
+			s.total++
+			p.total++
+			p.got[inf.got]++
+			s.confusion[inf.want][inf.got]++
+			if inf.want == inf.got {
+				s.correct++
+				p.correct++
+			}
+		}
+	}
+	return s
+}
+
+func (s *inferenceScore) accuracy() float64 {
+	return float64(s.correct) / float64(s.total)
+}
+
+// labels returns the types in the confusion matrix: the standard types first,
+// then any others which were wanted or inferred.
+func (s *inferenceScore) labels() []string {
+	seen := map[string]bool{}
+	labels := append([]string(nil), propertyTypes...)
+	for _, t := range labels {
+		seen[t] = true
+	}
+	var others []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			others = append(others, t)
+		}
+	}
+	for want, got := range s.confusion {
+		add(want)
+		for t := range got {
+			add(t)
+		}
+	}
+	sort.Strings(others)
+	return append(labels, others...)
+}
+
+// printInferenceScore prints the accuracy of each property's inferred type,
+// a confusion matrix of wanted against inferred types, and the overall score.
+func printInferenceScore(results []*testResult) {
+	s := scoreInferences(results)
+	if s.total == 0 {
+		return
+	}
+
+	color.Blue("TYPE INFERENCE")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  property\twanted\taccuracy\tinferred")
+	for _, key := range s.properties {
+		p := s.byProperty[key]
+		var got []string
+		for t := range p.got {
+			got = append(got, t)
+		}
+		sort.Strings(got)
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", key, p.want, formatAccuracy(p.correct, p.total), formatInferred(got, p.want))
+	}
+	w.Flush()
+
+	fmt.Println()
+	labels := s.labels()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprint(w, "  wanted \\ inferred")
+	for _, l := range labels {
+		fmt.Fprintf(w, "\t%s", l)
+	}
+	fmt.Fprintln(w)
+	for _, want := range labels {
+		row, ok := s.confusion[want]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(w, "  %s", want)
+		for _, got := range labels {
+			if n := row[got]; n > 0 {
+				fmt.Fprintf(w, "\t%d", n)
+			} else {
+				fmt.Fprint(w, "\t.")
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	w.Flush()
+
+	fmt.Printf("  type inference score: %s\n", formatAccuracy(s.correct, s.total))
+	flog.Printf("type inference score: %d/%d (%.1f%%)", s.correct, s.total, s.accuracy()*100)
+}
+
+func formatInferred(got []string, want string) string {
+	var text string
+	for i, t := range got {
+		if i > 0 {
+			text += ", "
+		}
+		if t == want {
+			text += t
+		} else {
+			text += color.RedString("%s", t)
+		}
+	}
+	return text
+}
+
+func formatAccuracy(correct, total int) string {
+	fraction := float64(correct) / float64(total)
+	text := fmt.Sprintf("%d/%d (%.1f%%)", correct, total, fraction*100)
+	switch {
+	case correct == total:
+		return color.GreenString("%s", text)
+	case fraction >= 0.5:
+		return color.YellowString("%s", text)
+	default:
+		return color.RedString("%s", text)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// invalidReportLimit is the number of false positives and false negatives listed for a test.
+const invalidReportLimit = 10
+
+// invalidScore compares the records a plugin marked invalid with the ground truth
+// from a manifest, to measure the precision and recall of its invalid record detection.
+type invalidScore struct {
+	key   int
+	truth map[string]*invalidRow
+	// marked records the keys of the records the plugin marked invalid.
+	marked map[string]bool
+
+	// truePositives counts the distinct keys of invalid records marked invalid.
+	truePositives  int
+	falsePositives []*plugin.PublishRecord
+	falseNegatives []*invalidRow
+	// unmatched counts records whose key couldn't be read, so they couldn't be scored.
+	unmatched int
+}
+
+func newInvalidScore(m *manifest) *invalidScore {
+	s := &invalidScore{
+		key:    m.Key,
+		truth:  map[string]*invalidRow{},
+		marked: map[string]bool{},
+	}
+	for _, row := range m.Invalid {
+		s.truth[invalidKey(row.Key)] = row
+	}
+	return s
+}
+
+// add scores a published record.
+func (s *invalidScore) add(record *plugin.PublishRecord) {
+	var data []interface{}
+	json.Unmarshal([]byte(record.Data), &data)
+	if s.key >= len(data) || data[s.key] == nil {
+		s.unmatched++
+		return
+	}
+	key := invalidKey(data[s.key])
+	_, shouldBeInvalid := s.truth[key]
+
+	switch {
+	case record.Invalid && shouldBeInvalid:
+		if !s.marked[key] {
+			s.truePositives++
+			s.marked[key] = true
+		}
+	case record.Invalid:
+		s.falsePositives = append(s.falsePositives, record)
+	}
+}
+
+// invalidKey formats a key so the same number matches however it was written,
+// and a number matches a string of its digits.
+func invalidKey(v interface{}) string {
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// finish finds the invalid rows which the plugin didn't mark invalid,
+// once all the records have been added.
+func (s *invalidScore) finish() {
+	for key, row := range s.truth {
+		if !s.marked[key] {
+			s.falseNegatives = append(s.falseNegatives, row)
+		}
+	}
+	sort.Slice(s.falseNegatives, func(i, j int) bool {
+		a, b := s.falseNegatives[i], s.falseNegatives[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+}
+
+// precision is the fraction of the records marked invalid which were invalid.
+func (s *invalidScore) precision() float64 {
+	marked := s.truePositives + len(s.falsePositives)
+	if marked == 0 {
+		return 1
+	}
+	return float64(s.truePositives) / float64(marked)
+}
+
+// recall is the fraction of the invalid records which were marked invalid.
+func (s *invalidScore) recall() float64 {
+	if len(s.truth) == 0 {
+		return 1
+	}
+	return float64(s.truePositives) / float64(len(s.truth))
+}
+
+func (s *invalidScore) String() string {
+	return fmt.Sprintf("precision %.1f%% (%d/%d), recall %.1f%% (%d/%d)",
+		s.precision()*100, s.truePositives, s.truePositives+len(s.falsePositives),
+		s.recall()*100, s.truePositives, len(s.truth))
+}
+
+// report records the score, and some of the records it got wrong, against result.
+func (s *invalidScore) report(result *testResult) {
+	summary := "invalid record detection: " + s.String()
+	if len(s.falsePositives) == 0 && len(s.falseNegatives) == 0 {
+		result.comment("%s", color.GreenString("%s", summary))
+	} else {
+		result.comment("%s", color.YellowString("%s", summary))
+	}
+	result.flog.Println(summary)
+
+	if s.unmatched > 0 {
+		result.comment("%s", color.YellowString("%d records had no key, so they couldn't be scored", s.unmatched))
+	}
+	for i, record := range s.falsePositives {
+		if i == invalidReportLimit {
+			result.comment("  ...and %d more false positives", len(s.falsePositives)-i)
+			break
+		}
+		result.comment("%s", color.RedString("false positive: record was marked invalid because %q: %s", record.Error, record.Data))
+	}
+	for i, row := range s.falseNegatives {
+		if i == invalidReportLimit {
+			result.comment("  ...and %d more false negatives", len(s.falseNegatives)-i)
+			break
+		}
+		result.comment("%s", color.RedString("false negative: record %v (%s line %d) has invalid values %s but wasn't marked invalid",
+			row.Key, row.File, row.Line, strings.Join(quoteAll(row.Values), ", ")))
+	}
+}
+
+func quoteAll(values []string) []string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = fmt.Sprintf("%q", v)
+	}
+	return quoted
+}
+
+// printInvalidScores prints the invalid record detection score of each test
+// which had ground truth.
+func printInvalidScores(results []*testResult) {
+	var scored []*testResult
+	for _, result := range results {
+		if result.invalid != nil {
+			scored = append(scored, result)
+		}
+	}
+	if len(scored) == 0 {
+		return
+	}
+
+	color.Blue("INVALID RECORDS")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  test\tprecision\trecall\tfalse positives\tfalse negatives")
+	for _, result := range scored {
+		s := result.invalid
+		fmt.Fprintf(w, "  %s\t%.1f%%\t%.1f%%\t%d\t%d\n", result.test.name(), s.precision()*100, s.recall()*100, len(s.falsePositives), len(s.falseNegatives))
+	}
+	w.Flush()
+}
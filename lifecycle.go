@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lifecycleTestCase sends signals to the plugin while it is publishing and
+// checks that it shuts down: it should exit promptly, exit with code 0 on
+// SIGINT, and not leave any of its child processes running. The plugin is
+// started again for each signal after the first, so this test should be last.
+type lifecycleTestCase struct {
+	n    string
+	d    string
+	glob string
+	// schema is published when each signal is sent.
+	schema plugin.Schema
+	// signals are sent in order, each to a different plugin process.
+	signals []syscall.Signal
+	// exitTimeout is how long the plugin has to exit after each signal.
+	exitTimeout time.Duration
+}
+
+func (t *lifecycleTestCase) name() string {
+	return t.n
+}
+
+func (t *lifecycleTestCase) description() string {
+	return t.d
+}
+
+func (t *lifecycleTestCase) exclusive() {}
+
+func (t *lifecycleTestCase) execute(client plugin.PluginClient, result *testResult) *testResult {
+	// conn is the connection to the plugin after a restart; the first one belongs to the caller.
+	var conn *grpc.ClientConn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	var failures []string
+	checked := 0
+	for _, sig := range t.signals {
+		if runtime.GOOS == "windows" && (sig == syscall.SIGINT || sig == syscall.SIGTERM) {
+			// The host can only kill the plugin on Windows, so there's no graceful exit to check.
+			result.comment("%s", color.YellowString("%s: skipped, because Windows can't send it to the plugin", signalName(sig)))
+			continue
+		}
+		if checked > 0 {
+			result.log("starting the plugin again...")
+			p, err := startPlugin(pluginArgs)
+			if err != nil {
+				return result.withErr(errors.WithMessage(err, "couldn't restart plugin"))
+			}
+			activePlugin = p
+			if conn != nil {
+				conn.Close()
+			}
+			if conn, err = dial(p.port); err != nil {
+				return result.withErr(err)
+			}
+			client = plugin.NewPluginClient(conn)
+		}
+		checked++
+		if err := t.checkSignal(client, activePlugin, sig, result); err != nil {
+			result.comment("%s", color.RedString("%s: %s", signalName(sig), err))
+			failures = append(failures, err.Error())
+		}
+	}
+
+	if len(failures) > 0 {
+		return result.withErr(errors.Errorf("plugin did not shut down correctly: %s", strings.Join(failures, "; ")))
+	}
+	if checked > 0 {
+		result.comment("%s", color.GreenString("plugin shut down correctly on every signal"))
+	}
+	return result
+}
+
+// checkSignal starts a publish from p, sends it sig while the publish is still
+// running, and checks how it exits.
+func (t *lifecycleTestCase) checkSignal(client plugin.PluginClient, p *pluginProcess, sig syscall.Signal, result *testResult) error {
+	settings := &plugin.Settings{
+		FileGlob: t.glob,
+	}
+	discover, err := discoverSchemas(client, settings, discoverTimeout, result.log)
+	if err != nil {
+		return errors.WithMessage(err, "discovery failed")
+	}
+	target := findSchemaIn(t.schema, discover.Schemas)
+	if target == nil {
+		return errors.Errorf("no schema matching %q was discovered", t.schema.Name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   target,
+	})
+	if err != nil {
+		return errors.Wrap(err, "publish failed")
+	}
+	// Wait for the first record so we know the publish has started. The rest
+	// are left unread, so the plugin is still publishing when the signal arrives.
+	if _, err = stream.Recv(); err != nil {
+		return errors.Wrap(err, "publish failed before the signal was sent")
+	}
+
+	// Remember the plugin's children now, because once it exits
+	// any it leaves behind will no longer be its descendants.
+	tree, _ := processTree(p.pid())
+	var children []int
+	for _, pid := range tree {
+		if pid != p.pid() {
+			children = append(children, pid)
+		}
+	}
+
+	result.log("sending %s to plugin (pid %d) during publish...", signalName(sig), p.pid())
+	p.expectExit()
+	start := time.Now()
+	// Like pressing Ctrl-C, this signals the whole process group, so a plugin
+	// started by a script gets the signal too.
+	if err = p.signal(sig); err != nil {
+		return errors.Wrapf(err, "couldn't send %s", signalName(sig))
+	}
+
+	var exitErr error
+	var leftover []string
+	select {
+	case <-p.exited:
+		elapsed := time.Since(start)
+		code := p.exitCode
+		result.comment("plugin exited with code %d %s after %s", code, elapsed.Round(time.Millisecond), signalName(sig))
+		switch {
+		case sig == syscall.SIGINT && code != 0:
+			exitErr = errors.Errorf("exited with code %d, not 0", code)
+		case code != 0:
+			result.comment("%s", color.YellowString("plugins should exit with code 0 after %s", signalName(sig)))
+		}
+		leftover = p.killOrphans(children)
+	case <-time.After(t.exitTimeout):
+		// Find what's still running before killing anything, because processes
+		// which have been killed but not reaped yet look like they're running.
+		leftover = p.killOrphans(children)
+		<-p.exited
+		exitErr = errors.Errorf("did not exit within %s", t.exitTimeout)
+	}
+
+	if len(leftover) > 0 {
+		result.comment("%s", color.RedString("child processes still running after the plugin exited: %s", strings.Join(leftover, ", ")))
+		if exitErr == nil {
+			exitErr = errors.Errorf("left %d child processes running", len(leftover))
+		}
+	}
+
+	return exitErr
+}
+
+func signalName(sig syscall.Signal) string {
+	switch sig {
+	case syscall.SIGINT:
+		return "SIGINT"
+	case syscall.SIGTERM:
+		return "SIGTERM"
+	}
+	return sig.String()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// manifestSuffix is the suffix of the ground truth manifests written by the generate command.
+const manifestSuffix = ".manifest.json"
+
+// manifestGlob matches ground truth manifests to run as tests, along with the standard tests.
+var manifestGlob string
+
+// manifestRecordChecks is the number of the invalid rows in a manifest
+// which are checked individually by its test.
+const manifestRecordChecks = 5
+
+func init() {
+	flag.StringVar(&manifestGlob, "manifest", "", "glob matching ground truth manifests (from the generate command) to run as tests")
+}
+
+// manifest is the ground truth for a generated data set: what a plugin
+// should discover from it and publish.
+type manifest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Glob matches the data set's files, relative to the manifest.
+	Glob       string              `json:"glob"`
+	Properties []*manifestProperty `json:"properties"`
+	Rows       int                 `json:"rows"`
+	Files      []*manifestFile     `json:"files"`
+	// Key is the index of the property which identifies each row.
+	Key     int           `json:"key"`
+	Invalid []*invalidRow `json:"invalid"`
+}
+
+type manifestProperty struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Nulls and Invalid are the number of empty and unparseable values.
+	Nulls   int `json:"nulls"`
+	Invalid int `json:"invalid"`
+}
+
+type manifestFile struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
+
+// invalidRow is a row with values which can't be parsed as their property's type.
+type invalidRow struct {
+	// Key is the row's value for the key property, as it appears in published data.
+	Key  interface{} `json:"key"`
+	File string      `json:"file"`
+	Line int         `json:"line"`
+	// Properties are the indexes of the invalid values, and Values are the values.
+	Properties []int    `json:"properties"`
+	Values     []string `json:"values"`
+}
+
+func (m *manifest) save(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+func loadManifest(path string) (*manifest, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := new(manifest)
+	if err = json.Unmarshal(b, m); err != nil {
+		return nil, errors.Wrapf(err, "bad manifest in %s", path)
+	}
+	if m.Key < 0 || m.Key >= len(m.Properties) {
+		return nil, errors.Errorf("bad manifest in %s: key %d isn't a property", path, m.Key)
+	}
+	return m, nil
+}
+
+// schema returns the schema the plugin should discover for the data set.
+func (m *manifest) schema() plugin.Schema {
+	s := plugin.Schema{Name: m.Name}
+	for _, p := range m.Properties {
+		s.Properties = append(s.Properties, &plugin.Property{Name: p.Name, Type: p.Type})
+	}
+	return s
+}
+
+// testCase returns a test which checks a plugin against the manifest,
+// which was loaded from dir.
+func (m *manifest) testCase(dir string) *standardTestCase {
+	schema := m.schema()
+	t := &standardTestCase{
+		n:               m.Name,
+		d:               m.Description,
+		glob:            filepath.Join(dir, m.Glob),
+		expectedSchemas: []plugin.Schema{schema},
+		publishSchema:   schema,
+		expectedCount:   m.Rows,
+		truth:           m,
+	}
+	if t.d == "" {
+		t.d = fmt.Sprintf("This test checks the generated %s data set against its ground truth manifest.", m.Name)
+	}
+
+	for i, row := range m.Invalid {
+		if i == manifestRecordChecks {
+			break
+		}
+		p := m.Properties[row.Properties[0]]
+		reason := fmt.Sprintf(" because %q is not a valid %s for %q (%s line %d)", row.Values[0], p.Type, p.Name, row.File, row.Line)
+		t.recordChecks = append(t.recordChecks, invalidRecordCheck(m.Key, row.Key, row.Properties[0], reason))
+	}
+	return t
+}
+
+// manifestTests returns a test for each manifest matching -manifest.
+func manifestTests() ([]test, error) {
+	if manifestGlob == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(manifestGlob)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad -manifest")
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no manifests match -manifest %q", manifestGlob)
+	}
+
+	var tests []test
+	for _, path := range paths {
+		if !strings.HasSuffix(path, manifestSuffix) {
+			continue
+		}
+		m, err := loadManifest(path)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, m.testCase(dir))
+	}
+	return tests, nil
+}
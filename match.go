@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// matchMode is how the properties of discovered schemas are matched with the expected properties.
+type matchMode string
+
+const (
+	// matchExact requires the same property names in the same order.
+	matchExact matchMode = "exact"
+	// matchName matches properties by name, in any order.
+	matchName matchMode = "name"
+	// matchNormalized matches properties by name, ignoring case and surrounding whitespace.
+	matchNormalized matchMode = "normalized"
+)
+
+var schemaMatch = matchExact
+
+func (m *matchMode) String() string {
+	return string(*m)
+}
+
+func (m *matchMode) Set(value string) error {
+	switch mode := matchMode(value); mode {
+	case matchExact, matchName, matchNormalized:
+		*m = mode
+		return nil
+	}
+	return errors.Errorf("must be %s, %s or %s", matchExact, matchName, matchNormalized)
+}
+
+func init() {
+	flag.Var(&schemaMatch, "schema-match", "how discovered properties are matched with expected ones: exact (same names, same order), name (by name, any order) or normalized (by name, ignoring case and surrounding whitespace)")
+}
+
+// normalizeName returns the form of a property name which is compared in the current match mode.
+func normalizeName(name string) string {
+	if schemaMatch == matchNormalized {
+		return strings.ToLower(strings.TrimSpace(name))
+	}
+	return name
+}
+
+// schemaAlignment pairs the properties of an expected schema with the
+// properties of a discovered schema.
+type schemaAlignment struct {
+	want plugin.Schema
+	got  *plugin.Schema
+	// gotIndex is the index of the discovered property matching each expected property, or -1.
+	gotIndex []int
+	// wantIndex is the index of the expected property matching each discovered property, or -1.
+	wantIndex []int
+}
+
+// alignSchema matches the properties of got with want using the current match mode.
+func alignSchema(want plugin.Schema, got *plugin.Schema) *schemaAlignment {
+	a := &schemaAlignment{
+		want:      want,
+		got:       got,
+		gotIndex:  make([]int, len(want.Properties)),
+		wantIndex: make([]int, len(got.Properties)),
+	}
+	for j := range a.wantIndex {
+		a.wantIndex[j] = -1
+	}
+
+	if schemaMatch == matchExact {
+		namesMatch, _ := checkSchema(want, got)
+		for i := range a.gotIndex {
+			a.gotIndex[i] = -1
+			if namesMatch {
+				a.gotIndex[i] = i
+				a.wantIndex[i] = i
+			}
+		}
+		return a
+	}
+
+	byName := map[string]int{}
+	for j, p := range got.Properties {
+		name := normalizeName(p.Name)
+		if _, ok := byName[name]; !ok {
+			byName[name] = j
+		}
+	}
+	for i, p := range want.Properties {
+		a.gotIndex[i] = -1
+		if j, ok := byName[normalizeName(p.Name)]; ok && a.wantIndex[j] < 0 {
+			a.gotIndex[i] = j
+			a.wantIndex[j] = i
+		}
+	}
+	return a
+}
+
+// findAlignment aligns want with the discovered schema which matches it best: the
+// one with the most matching properties, preferring one with the same name.
+// It returns nil if no schema has any matching properties.
+func findAlignment(want plugin.Schema, in []*plugin.Schema) *schemaAlignment {
+	var best *schemaAlignment
+	bestScore := 0
+	for _, got := range in {
+		a := alignSchema(want, got)
+		score := 2 * a.matched()
+		if normalizeName(got.Name) == normalizeName(want.Name) {
+			score++
+		}
+		if a.matched() > 0 && score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+func (a *schemaAlignment) matched() int {
+	n := 0
+	for _, j := range a.gotIndex {
+		if j >= 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// complete reports whether every expected property was discovered.
+func (a *schemaAlignment) complete() bool {
+	return a.matched() == len(a.want.Properties)
+}
+
+// typesMatch reports whether every expected property was discovered with the expected type.
+func (a *schemaAlignment) typesMatch() bool {
+	if !a.complete() {
+		return false
+	}
+	for i, j := range a.gotIndex {
+		if a.want.Properties[i].Type != a.got.Properties[j].Type {
+			return false
+		}
+	}
+	return true
+}
+
+// identity reports whether the discovered properties are exactly the expected ones, in order.
+func (a *schemaAlignment) identity() bool {
+	if len(a.want.Properties) != len(a.got.Properties) {
+		return false
+	}
+	for i, j := range a.gotIndex {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
+
+// report comments on each way the discovered properties differ from the
+// expected properties, returning an error if any expected properties are missing.
+func (a *schemaAlignment) report(result *testResult) error {
+	var missing []string
+	for i, j := range a.gotIndex {
+		want := a.want.Properties[i].Name
+		switch {
+		case j < 0:
+			missing = append(missing, want)
+			result.comment("%s", color.RedString("property %q of schema %s was not discovered", want, a.want.Name))
+		case a.got.Properties[j].Name != want:
+			result.comment("%s", color.YellowString("property %q of schema %s was discovered as %q", want, a.want.Name, a.got.Properties[j].Name))
+		}
+	}
+	for j, i := range a.wantIndex {
+		if i < 0 {
+			result.comment("%s", color.YellowString("property %q was discovered on schema %s, but wasn't expected", a.got.Properties[j].Name, a.want.Name))
+		}
+	}
+	if a.complete() && !a.identity() && len(a.want.Properties) == len(a.got.Properties) {
+		var order []string
+		for _, p := range a.got.Properties {
+			order = append(order, p.Name)
+		}
+		result.comment("%s", color.YellowString("properties of schema %s were discovered in a different order than the file: %s", a.want.Name, strings.Join(order, ", ")))
+	}
+
+	if len(missing) > 0 {
+		return errors.Errorf("schema %s is missing properties %s", a.want.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// record returns r with its data and errors rearranged into the order of
+// the expected properties, so they can be checked by expected index.
+func (a *schemaAlignment) record(r *plugin.PublishRecord) *plugin.PublishRecord {
+	if a.identity() {
+		return r
+	}
+
+	var data []interface{}
+	if err := json.Unmarshal([]byte(r.Data), &data); err != nil {
+		return r
+	}
+	aligned := make([]interface{}, len(a.gotIndex))
+	for i, j := range a.gotIndex {
+		if j >= 0 && j < len(data) {
+			aligned[i] = data[j]
+		}
+	}
+	b, _ := json.Marshal(aligned)
+
+	out := *r
+	out.Data = string(b)
+	out.Errors = nil
+	for _, e := range r.Errors {
+		e := *e
+		if int(e.PropertyIndex) < len(a.wantIndex) {
+			if i := a.wantIndex[e.PropertyIndex]; i >= 0 {
+				e.PropertyIndex = int32(i)
+			}
+		}
+		out.Errors = append(out.Errors, &e)
+	}
+	return &out
+}
+
+// typeMismatches describes the properties whose discovered type isn't the expected type.
+func (a *schemaAlignment) typeMismatches() string {
+	w := new(strings.Builder)
+	for i, j := range a.gotIndex {
+		if j < 0 {
+			continue
+		}
+		wp := a.want.Properties[i]
+		gp := a.got.Properties[j]
+		if wp.Type != gp.Type {
+			fmt.Fprintf(w, "%s: wanted %s, got %s; ", wp.Name, color.GreenString(wp.Type), color.RedString(gp.Type))
+		}
+	}
+	return w.String()
+}
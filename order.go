@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// orderFlag is the record order the standard tests ask for when they publish.
+type orderFlag plugin.RecordOrder
+
+var publishOrder orderFlag
+
+// orderNames are the values of -order.
+var orderNames = map[string]plugin.RecordOrder{
+	"unspecified": plugin.RecordOrder_RECORD_ORDER_UNSPECIFIED,
+	"file":        plugin.RecordOrder_RECORD_ORDER_FILE,
+}
+
+func (o *orderFlag) String() string {
+	for name, order := range orderNames {
+		if plugin.RecordOrder(*o) == order {
+			return name
+		}
+	}
+	return plugin.RecordOrder(*o).String()
+}
+
+func (o *orderFlag) Set(value string) error {
+	order, ok := orderNames[value]
+	if !ok {
+		return errors.New("must be unspecified or file")
+	}
+	*o = orderFlag(order)
+	return nil
+}
+
+func init() {
+	flag.Var(&publishOrder, "order", "record order the tests ask for when publishing: unspecified, or file (checking that records arrive in file and line order)")
+}
+
+// position is where a record came from, in file order.
+type position struct {
+	file int
+	line int64
+}
+
+func (p position) before(q position) bool {
+	return p.file < q.file || p.file == q.file && p.line < q.line
+}
+
+// orderCheck checks that records arrive in file order. It places each record
+// using its provenance, if the plugin sent it, and otherwise by looking up
+// the value of a key column in the files.
+type orderCheck struct {
+	files     []string
+	fileIndex map[string]int
+	// key is the index of the key column, or -1 if there isn't one,
+	// and keyPositions are where each value of it is in the files.
+	key          int
+	keyPositions map[string]position
+
+	last         position
+	count        int
+	byProvenance int
+	byKey        int
+	unplaced     int
+	// violation describes the first record which arrived out of order.
+	violation string
+}
+
+// newOrderCheck reads the files for schema (the expected schema of a test
+// which publishes from glob) to find where each record should be.
+func newOrderCheck(glob string, schema plugin.Schema) (*orderCheck, error) {
+	files, err := filesForSchema(glob, schema)
+	if err != nil {
+		return nil, err
+	}
+	c := &orderCheck{
+		files:        files,
+		fileIndex:    map[string]int{},
+		key:          -1,
+		keyPositions: map[string]position{},
+		last:         position{file: -1},
+	}
+	for i, file := range files {
+		c.fileIndex[file] = i
+		c.fileIndex[filepath.Base(file)] = i
+	}
+
+	_, keys, err := detectKeys(files)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return c, nil
+	}
+	c.key = keys[0]
+	for i, file := range files {
+		if err = c.readKeys(i, file); err != nil {
+			return nil, errors.WithMessage(err, "couldn't read keys from "+file)
+		}
+	}
+	return c, nil
+}
+
+func (c *orderCheck) readKeys(index int, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := &lineReader{r: bufio.NewReader(f)}
+	if _, _, err = r.read(); err != nil {
+		return err
+	}
+	for {
+		row, line, err := r.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c.key < len(row) {
+			c.keyPositions[row[c.key]] = position{file: index, line: line}
+		}
+	}
+}
+
+// lineReader reads the rows of a CSV file along with the line each row starts
+// on, counting the blank lines and the newlines in quoted values, so the lines
+// are the same as the ones plugins give in provenance. (csv.Reader only has
+// FieldPos from Go 1.17.)
+type lineReader struct {
+	r    *bufio.Reader
+	line int64
+}
+
+func (l *lineReader) read() (row []string, line int64, err error) {
+	var text strings.Builder
+	quoted := false
+	for quoted || text.Len() == 0 {
+		s, err := l.r.ReadString('\n')
+		if s == "" {
+			if err == io.EOF && text.Len() > 0 {
+				// The file ended inside a quoted value.
+				break
+			}
+			return nil, 0, err
+		}
+		l.line++
+		if text.Len() == 0 {
+			if strings.TrimRight(s, "\r\n") == "" {
+				// csv.Reader skips blank lines.
+				continue
+			}
+			line = l.line
+		}
+		text.WriteString(s)
+		quoted = inQuotes(s, quoted)
+	}
+
+	r := csv.NewReader(strings.NewReader(text.String()))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	row, err = r.Read()
+	return row, line, err
+}
+
+// inQuotes reports whether a quoted value is still open at the end of a line
+// of CSV, given whether one was open at its start. It follows csv.Reader with
+// LazyQuotes: a quote only opens a value at the start of a field, and inside
+// one a quote which isn't doubled or followed by the end of the field is kept.
+func inQuotes(line string, quoted bool) bool {
+	fieldStart := !quoted
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quoted && c == '"':
+			next := byte('\n')
+			if i+1 < len(line) {
+				next = line[i+1]
+			}
+			switch next {
+			case '"':
+				i++
+			case ',', '\r', '\n':
+				quoted = false
+			}
+		case fieldStart && c == '"':
+			quoted = true
+		}
+		fieldStart = !quoted && c == ','
+	}
+	return quoted
+}
+
+// evaluate places a record, whose data is in the order of the expected properties.
+func (c *orderCheck) evaluate(record *plugin.PublishRecord, data []interface{}) {
+	c.count++
+	p, ok := c.place(record, data)
+	if !ok {
+		c.unplaced++
+		return
+	}
+	if c.violation == "" && !c.last.before(p) {
+		c.violation = fmt.Sprintf("record %d (%s) arrived after a record from %s", c.count, c.describe(p), c.describe(c.last))
+	}
+	c.last = p
+}
+
+func (c *orderCheck) place(record *plugin.PublishRecord, data []interface{}) (position, bool) {
+	if prov := record.Provenance; prov != nil {
+		i, ok := c.fileIndex[prov.File]
+		if !ok {
+			i, ok = c.fileIndex[filepath.Base(prov.File)]
+		}
+		if ok {
+			c.byProvenance++
+			return position{file: i, line: prov.Line}, true
+		}
+	}
+	if c.key < 0 {
+		return position{}, false
+	}
+	var key string
+	switch v := valueAt(data, c.key).(type) {
+	case nil:
+		return position{}, false
+	case float64:
+		key = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		key = fmt.Sprint(v)
+	}
+	p, ok := c.keyPositions[key]
+	if ok {
+		c.byKey++
+	}
+	return p, ok
+}
+
+func (c *orderCheck) describe(p position) string {
+	return fmt.Sprintf("%s line %d", filepath.Base(c.files[p.file]), p.line)
+}
+
+// report comments on how the order was checked, returning an error if the
+// records didn't arrive in file order.
+func (c *orderCheck) report(result *testResult) error {
+	if c.byProvenance == 0 && c.byKey == 0 {
+		result.comment("%s", color.YellowString("couldn't check the record order, because the records have no provenance and the files have no key column"))
+		return nil
+	}
+	how := fmt.Sprintf("%d records placed by provenance and %d by key column", c.byProvenance, c.byKey)
+	if c.unplaced > 0 {
+		result.comment("%s", color.YellowString("couldn't place %d of %d records in the files to check their order", c.unplaced, c.count))
+	}
+	if c.violation != "" {
+		return errors.Errorf("records were not published in file order (%s): %s", how, c.violation)
+	}
+	result.comment("%s", color.GreenString("records were published in file order (%s)", how))
+	return nil
+}
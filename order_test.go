@@ -0,0 +1,138 @@
+package main
+
+import (
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var orderSchema = plugin.Schema{
+	Name: "notes",
+	Properties: []*plugin.Property{
+		{Name: "id", Type: "integer"},
+		{Name: "note", Type: "string"},
+	},
+}
+
+// writeOrderFiles writes CSV files for orderSchema to a temp dir, returning the dir.
+func writeOrderFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func checkKeyPositions(t *testing.T, c *orderCheck, want map[string]position) {
+	if c.key != 0 {
+		t.Fatalf("key column = %d, want 0", c.key)
+	}
+	for key, p := range want {
+		if got, ok := c.keyPositions[key]; !ok || got != p {
+			t.Errorf("position of key %s = %+v, want %+v", key, got, p)
+		}
+	}
+}
+
+func TestOrderCheckQuotedLines(t *testing.T) {
+	dir := writeOrderFiles(t, map[string]string{
+		"a.csv": "id,note\n" +
+			"1,\"first\nsecond\"\n" +
+			"2,plain\n" +
+			"3,\"say \"\"hi\"\"\n\n,there\"\n" +
+			"4,\"a,b\"\n" +
+			"5,5\"6\n" +
+			"6,last",
+	})
+	defer os.RemoveAll(dir)
+
+	c, err := newOrderCheck(filepath.Join(dir, "*.csv"), orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKeyPositions(t, c, map[string]position{
+		"1": {0, 2},
+		"2": {0, 4},
+		// The blank line is inside the quoted value, so it's part of the row.
+		"3": {0, 5},
+		"4": {0, 8},
+		// A quote inside an unquoted value doesn't start a quoted value.
+		"5": {0, 9},
+		"6": {0, 10},
+	})
+}
+
+func TestOrderCheckBlankLines(t *testing.T) {
+	dir := writeOrderFiles(t, map[string]string{
+		"a.csv": "id,note\r\n\r\n1,one\r\n2,two\r\n\r\n\r\n3,three\r\n\r\n",
+		"b.csv": "id,note\n4,four\n\n5,five\n",
+	})
+	defer os.RemoveAll(dir)
+
+	c, err := newOrderCheck(filepath.Join(dir, "*.csv"), orderSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkKeyPositions(t, c, map[string]position{
+		"1": {0, 3},
+		"2": {0, 4},
+		"3": {0, 7},
+		"4": {1, 2},
+		"5": {1, 4},
+	})
+}
+
+func TestOrderCheckMixedPlacement(t *testing.T) {
+	files := map[string]string{
+		"a.csv": "id,note\n1,one\n\n2,two\n3,\"three\nlines\n\"\n4,four\n",
+		"b.csv": "id,note\n5,five\n6,six\n",
+	}
+	dir := writeOrderFiles(t, files)
+	defer os.RemoveAll(dir)
+	a, b := filepath.Join(dir, "a.csv"), filepath.Join(dir, "b.csv")
+
+	type published struct {
+		id   float64
+		file string
+		line int64
+	}
+	tests := []struct {
+		name      string
+		records   []published
+		violation bool
+	}{
+		{"in order", []published{{1, a, 2}, {2, "", 0}, {3, a, 5}, {4, "", 0}, {5, "b.csv", 2}, {6, "", 0}}, false},
+		{"in order, placed by key", []published{{1, "", 0}, {2, "", 0}, {3, "", 0}, {4, "", 0}, {5, "", 0}, {6, "", 0}}, false},
+		{"in order, placed by provenance", []published{{1, a, 2}, {2, a, 4}, {3, a, 5}, {4, a, 8}, {5, b, 2}, {6, b, 3}}, false},
+		{"key before provenance", []published{{1, a, 2}, {4, "", 0}, {3, a, 5}, {5, b, 2}, {6, b, 3}}, true},
+		{"files swapped", []published{{5, b, 2}, {6, "", 0}, {1, a, 2}, {2, "", 0}}, true},
+	}
+	for _, test := range tests {
+		c, err := newOrderCheck(filepath.Join(dir, "*.csv"), orderSchema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byProvenance := 0
+		for _, p := range test.records {
+			record := &plugin.PublishRecord{}
+			if p.file != "" {
+				record.Provenance = &plugin.Provenance{File: p.file, Line: p.line}
+				byProvenance++
+			}
+			c.evaluate(record, []interface{}{p.id, "note"})
+		}
+		if c.unplaced != 0 || c.byProvenance != byProvenance || c.byKey != len(test.records)-byProvenance {
+			t.Errorf("%s: placed %d by provenance and %d by key, with %d unplaced", test.name, c.byProvenance, c.byKey, c.unplaced)
+		}
+		if got := c.violation != ""; got != test.violation {
+			t.Errorf("%s: violation %q, want one: %v", test.name, c.violation, test.violation)
+		}
+	}
+}
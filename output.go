@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"github.com/fatih/color"
+	"io"
+	golog "log"
+	"os"
+	"sync"
+)
+
+// pluginOutputHistory is the number of recent lines of plugin output kept by outputTracker.
+const pluginOutputHistory = 100
+
+// outputTracker keeps count of the lines the plugin has written to stdout and stderr,
+// and remembers the most recent ones, so tests can check what the plugin was doing.
+type outputTracker struct {
+	mu     sync.Mutex
+	lines  int
+	recent []string
+}
+
+var pluginOutput = new(outputTracker)
+
+var pluginLog = golog.New(os.Stdout, color.YellowString("PLUGIN|"), golog.Ltime|golog.Lmicroseconds)
+
+func (o *outputTracker) record(line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.lines++
+	o.recent = append(o.recent, line)
+	if len(o.recent) > pluginOutputHistory {
+		o.recent = o.recent[len(o.recent)-pluginOutputHistory:]
+	}
+}
+
+// count returns the number of lines written so far.
+func (o *outputTracker) count() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.lines
+}
+
+// since returns the lines written after the first n lines,
+// as far back as the history goes.
+func (o *outputTracker) since(n int) []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	missed := o.lines - n
+	if missed <= 0 {
+		return nil
+	}
+	if missed > len(o.recent) {
+		missed = len(o.recent)
+	}
+	return append([]string(nil), o.recent[len(o.recent)-missed:]...)
+}
+
+// copyPluginOutput logs each line from r as plugin output and records it.
+func copyPluginOutput(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		pluginOutput.record(scanner.Text())
+		pluginLog.Print(scanner.Text())
+	}
+}
+
+// monitorStderr writes the plugin's stderr to stdout.
+func monitorStderr(r io.Reader) {
+	copyPluginOutput(bufio.NewScanner(r))
+}
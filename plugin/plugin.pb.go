@@ -23,6 +23,66 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type RecordOrder int32
+
+const (
+	// The records can be published in any order, for example reading
+	// several files at once.
+	RecordOrder_RECORD_ORDER_UNSPECIFIED RecordOrder = 0
+	// The records must be published in file order: the files in lexical
+	// order of their paths, and the rows of each file in line order.
+	RecordOrder_RECORD_ORDER_FILE RecordOrder = 1
+)
+
+var RecordOrder_name = map[int32]string{
+	0: "RECORD_ORDER_UNSPECIFIED",
+	1: "RECORD_ORDER_FILE",
+}
+var RecordOrder_value = map[string]int32{
+	"RECORD_ORDER_UNSPECIFIED": 0,
+	"RECORD_ORDER_FILE":        1,
+}
+
+func (x RecordOrder) String() string {
+	return proto.EnumName(RecordOrder_name, int32(x))
+}
+func (RecordOrder) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{0}
+}
+
+type ErrorCode int32
+
+const (
+	// The plugin did not say what was wrong with the value.
+	ErrorCode_ERROR_CODE_UNKNOWN ErrorCode = 0
+	// The value could not be parsed as the property's type.
+	ErrorCode_ERROR_CODE_INVALID_TYPE ErrorCode = 1
+	// The row had no value for the property.
+	ErrorCode_ERROR_CODE_MISSING_VALUE ErrorCode = 2
+	// The row had more values than the schema has properties.
+	ErrorCode_ERROR_CODE_EXTRA_VALUE ErrorCode = 3
+)
+
+var ErrorCode_name = map[int32]string{
+	0: "ERROR_CODE_UNKNOWN",
+	1: "ERROR_CODE_INVALID_TYPE",
+	2: "ERROR_CODE_MISSING_VALUE",
+	3: "ERROR_CODE_EXTRA_VALUE",
+}
+var ErrorCode_value = map[string]int32{
+	"ERROR_CODE_UNKNOWN":       0,
+	"ERROR_CODE_INVALID_TYPE":  1,
+	"ERROR_CODE_MISSING_VALUE": 2,
+	"ERROR_CODE_EXTRA_VALUE":   3,
+}
+
+func (x ErrorCode) String() string {
+	return proto.EnumName(ErrorCode_name, int32(x))
+}
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{1}
+}
+
 // The request message containing the user's name.
 type DiscoverRequest struct {
 	// In a real plugin the settings would be conveyed in a JSON object
@@ -39,7 +99,7 @@ func (m *DiscoverRequest) Reset()         { *m = DiscoverRequest{} }
 func (m *DiscoverRequest) String() string { return proto.CompactTextString(m) }
 func (*DiscoverRequest) ProtoMessage()    {}
 func (*DiscoverRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{0}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{0}
 }
 func (m *DiscoverRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiscoverRequest.Unmarshal(m, b)
@@ -85,7 +145,7 @@ func (m *Settings) Reset()         { *m = Settings{} }
 func (m *Settings) String() string { return proto.CompactTextString(m) }
 func (*Settings) ProtoMessage()    {}
 func (*Settings) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{1}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{1}
 }
 func (m *Settings) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Settings.Unmarshal(m, b)
@@ -124,7 +184,7 @@ func (m *DiscoverResponse) Reset()         { *m = DiscoverResponse{} }
 func (m *DiscoverResponse) String() string { return proto.CompactTextString(m) }
 func (*DiscoverResponse) ProtoMessage()    {}
 func (*DiscoverResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{2}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{2}
 }
 func (m *DiscoverResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DiscoverResponse.Unmarshal(m, b)
@@ -151,6 +211,208 @@ func (m *DiscoverResponse) GetSchemas() []*Schema {
 	return nil
 }
 
+type DiscoverEvent struct {
+	// Types that are valid to be assigned to Event:
+	//	*DiscoverEvent_Progress
+	//	*DiscoverEvent_Schema
+	Event                isDiscoverEvent_Event `protobuf_oneof:"event"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *DiscoverEvent) Reset()         { *m = DiscoverEvent{} }
+func (m *DiscoverEvent) String() string { return proto.CompactTextString(m) }
+func (*DiscoverEvent) ProtoMessage()    {}
+func (*DiscoverEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{3}
+}
+func (m *DiscoverEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiscoverEvent.Unmarshal(m, b)
+}
+func (m *DiscoverEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiscoverEvent.Marshal(b, m, deterministic)
+}
+func (dst *DiscoverEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiscoverEvent.Merge(dst, src)
+}
+func (m *DiscoverEvent) XXX_Size() int {
+	return xxx_messageInfo_DiscoverEvent.Size(m)
+}
+func (m *DiscoverEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiscoverEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiscoverEvent proto.InternalMessageInfo
+
+type isDiscoverEvent_Event interface {
+	isDiscoverEvent_Event()
+}
+
+type DiscoverEvent_Progress struct {
+	Progress *DiscoverProgress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"`
+}
+
+type DiscoverEvent_Schema struct {
+	Schema *Schema `protobuf:"bytes,2,opt,name=schema,proto3,oneof"`
+}
+
+func (*DiscoverEvent_Progress) isDiscoverEvent_Event() {}
+
+func (*DiscoverEvent_Schema) isDiscoverEvent_Event() {}
+
+func (m *DiscoverEvent) GetEvent() isDiscoverEvent_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (m *DiscoverEvent) GetProgress() *DiscoverProgress {
+	if x, ok := m.GetEvent().(*DiscoverEvent_Progress); ok {
+		return x.Progress
+	}
+	return nil
+}
+
+func (m *DiscoverEvent) GetSchema() *Schema {
+	if x, ok := m.GetEvent().(*DiscoverEvent_Schema); ok {
+		return x.Schema
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*DiscoverEvent) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _DiscoverEvent_OneofMarshaler, _DiscoverEvent_OneofUnmarshaler, _DiscoverEvent_OneofSizer, []interface{}{
+		(*DiscoverEvent_Progress)(nil),
+		(*DiscoverEvent_Schema)(nil),
+	}
+}
+
+func _DiscoverEvent_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*DiscoverEvent)
+	// event
+	switch x := m.Event.(type) {
+	case *DiscoverEvent_Progress:
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Progress); err != nil {
+			return err
+		}
+	case *DiscoverEvent_Schema:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Schema); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("DiscoverEvent.Event has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _DiscoverEvent_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*DiscoverEvent)
+	switch tag {
+	case 1: // event.progress
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(DiscoverProgress)
+		err := b.DecodeMessage(msg)
+		m.Event = &DiscoverEvent_Progress{msg}
+		return true, err
+	case 2: // event.schema
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Schema)
+		err := b.DecodeMessage(msg)
+		m.Event = &DiscoverEvent_Schema{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _DiscoverEvent_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*DiscoverEvent)
+	// event
+	switch x := m.Event.(type) {
+	case *DiscoverEvent_Progress:
+		s := proto.Size(x.Progress)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case *DiscoverEvent_Schema:
+		s := proto.Size(x.Schema)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+type DiscoverProgress struct {
+	// Number of files which match the glob, if known.
+	FilesTotal int64 `protobuf:"varint,1,opt,name=filesTotal,proto3" json:"filesTotal,omitempty"`
+	// Number of files which have been examined so far.
+	FilesDone int64 `protobuf:"varint,2,opt,name=filesDone,proto3" json:"filesDone,omitempty"`
+	// The file currently being examined.
+	CurrentFile          string   `protobuf:"bytes,3,opt,name=currentFile,proto3" json:"currentFile,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DiscoverProgress) Reset()         { *m = DiscoverProgress{} }
+func (m *DiscoverProgress) String() string { return proto.CompactTextString(m) }
+func (*DiscoverProgress) ProtoMessage()    {}
+func (*DiscoverProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{4}
+}
+func (m *DiscoverProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiscoverProgress.Unmarshal(m, b)
+}
+func (m *DiscoverProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiscoverProgress.Marshal(b, m, deterministic)
+}
+func (dst *DiscoverProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiscoverProgress.Merge(dst, src)
+}
+func (m *DiscoverProgress) XXX_Size() int {
+	return xxx_messageInfo_DiscoverProgress.Size(m)
+}
+func (m *DiscoverProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiscoverProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiscoverProgress proto.InternalMessageInfo
+
+func (m *DiscoverProgress) GetFilesTotal() int64 {
+	if m != nil {
+		return m.FilesTotal
+	}
+	return 0
+}
+
+func (m *DiscoverProgress) GetFilesDone() int64 {
+	if m != nil {
+		return m.FilesDone
+	}
+	return 0
+}
+
+func (m *DiscoverProgress) GetCurrentFile() string {
+	if m != nil {
+		return m.CurrentFile
+	}
+	return ""
+}
+
 type Schema struct {
 	// The unique name of the schema; if there is no unique name
 	// the plugin can generate one.
@@ -162,7 +424,8 @@ type Schema struct {
 	// Hint: this is a good place to store the file paths of all the
 	// files which contain records with this schema.
 	Settings string `protobuf:"bytes,2,opt,name=settings,proto3" json:"settings,omitempty"`
-	// Array of the properties discovered for this schema.
+	// Array of the properties discovered for this schema,
+	// in the order they have in the CSV.
 	Properties           []*Property `protobuf:"bytes,3,rep,name=properties,proto3" json:"properties,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
@@ -173,7 +436,7 @@ func (m *Schema) Reset()         { *m = Schema{} }
 func (m *Schema) String() string { return proto.CompactTextString(m) }
 func (*Schema) ProtoMessage()    {}
 func (*Schema) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{3}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{5}
 }
 func (m *Schema) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Schema.Unmarshal(m, b)
@@ -219,7 +482,17 @@ type Property struct {
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	// Type of the property; can be "string", "integer", "number", "datetime", "boolean"
 	// This should be inferred if possible by analyzing the data.
-	Type                 string   `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// This is an optional part of the challenge; you can pass the tests
+	// without populating this field.
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// Statistics about the values found for this property during discovery,
+	// used to help users review the inferred type. This is optional.
+	Stats *PropertyStats `protobuf:"bytes,3,opt,name=stats,proto3" json:"stats,omitempty"`
+	// This should be set to true if the property is a key for the schema,
+	// meaning that every record has a value for it and no two records
+	// (across all the files in the schema) have the same value.
+	// This is optional, but if you set it, it must be correct.
+	IsKey                bool     `protobuf:"varint,4,opt,name=isKey,proto3" json:"isKey,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -229,7 +502,7 @@ func (m *Property) Reset()         { *m = Property{} }
 func (m *Property) String() string { return proto.CompactTextString(m) }
 func (*Property) ProtoMessage()    {}
 func (*Property) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{4}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{6}
 }
 func (m *Property) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Property.Unmarshal(m, b)
@@ -263,19 +536,132 @@ func (m *Property) GetType() string {
 	return ""
 }
 
+func (m *Property) GetStats() *PropertyStats {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *Property) GetIsKey() bool {
+	if m != nil {
+		return m.IsKey
+	}
+	return false
+}
+
+type PropertyStats struct {
+	// Number of rows examined.
+	RowCount int64 `protobuf:"varint,1,opt,name=rowCount,proto3" json:"rowCount,omitempty"`
+	// Number of rows where the value was empty or null.
+	NullCount int64 `protobuf:"varint,2,opt,name=nullCount,proto3" json:"nullCount,omitempty"`
+	// Estimated number of distinct non-null values.
+	DistinctEstimate int64 `protobuf:"varint,3,opt,name=distinctEstimate,proto3" json:"distinctEstimate,omitempty"`
+	// Smallest and largest non-null values, formatted as they would
+	// be in the CSV. For strings these are compared lexically.
+	Min string `protobuf:"bytes,4,opt,name=min,proto3" json:"min,omitempty"`
+	Max string `protobuf:"bytes,5,opt,name=max,proto3" json:"max,omitempty"`
+	// A few example values from the data.
+	SampleValues []string `protobuf:"bytes,6,rep,name=sampleValues,proto3" json:"sampleValues,omitempty"`
+	// Fraction (0 to 1) of non-null values which can be parsed
+	// as the inferred type.
+	TypeConfidence       float64  `protobuf:"fixed64,7,opt,name=typeConfidence,proto3" json:"typeConfidence,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PropertyStats) Reset()         { *m = PropertyStats{} }
+func (m *PropertyStats) String() string { return proto.CompactTextString(m) }
+func (*PropertyStats) ProtoMessage()    {}
+func (*PropertyStats) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{7}
+}
+func (m *PropertyStats) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PropertyStats.Unmarshal(m, b)
+}
+func (m *PropertyStats) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PropertyStats.Marshal(b, m, deterministic)
+}
+func (dst *PropertyStats) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PropertyStats.Merge(dst, src)
+}
+func (m *PropertyStats) XXX_Size() int {
+	return xxx_messageInfo_PropertyStats.Size(m)
+}
+func (m *PropertyStats) XXX_DiscardUnknown() {
+	xxx_messageInfo_PropertyStats.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PropertyStats proto.InternalMessageInfo
+
+func (m *PropertyStats) GetRowCount() int64 {
+	if m != nil {
+		return m.RowCount
+	}
+	return 0
+}
+
+func (m *PropertyStats) GetNullCount() int64 {
+	if m != nil {
+		return m.NullCount
+	}
+	return 0
+}
+
+func (m *PropertyStats) GetDistinctEstimate() int64 {
+	if m != nil {
+		return m.DistinctEstimate
+	}
+	return 0
+}
+
+func (m *PropertyStats) GetMin() string {
+	if m != nil {
+		return m.Min
+	}
+	return ""
+}
+
+func (m *PropertyStats) GetMax() string {
+	if m != nil {
+		return m.Max
+	}
+	return ""
+}
+
+func (m *PropertyStats) GetSampleValues() []string {
+	if m != nil {
+		return m.SampleValues
+	}
+	return nil
+}
+
+func (m *PropertyStats) GetTypeConfidence() float64 {
+	if m != nil {
+		return m.TypeConfidence
+	}
+	return 0
+}
+
 type PublishRequest struct {
-	Settings             *Settings `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
-	Schema               *Schema   `protobuf:"bytes,2,opt,name=schema,proto3" json:"schema,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	// The settings will be the same as the settings sent to the Discover method.
+	Settings *Settings `protobuf:"bytes,1,opt,name=settings,proto3" json:"settings,omitempty"`
+	// The schema will be one of the schemas returned by the Discover method.
+	Schema *Schema `protobuf:"bytes,2,opt,name=schema,proto3" json:"schema,omitempty"`
+	// The order the host needs the records in. Plugins which don't support
+	// an order other than RECORD_ORDER_UNSPECIFIED should return an error.
+	Order                RecordOrder `protobuf:"varint,3,opt,name=order,proto3,enum=plugin.RecordOrder" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *PublishRequest) Reset()         { *m = PublishRequest{} }
 func (m *PublishRequest) String() string { return proto.CompactTextString(m) }
 func (*PublishRequest) ProtoMessage()    {}
 func (*PublishRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{5}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{8}
 }
 func (m *PublishRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PublishRequest.Unmarshal(m, b)
@@ -309,6 +695,13 @@ func (m *PublishRequest) GetSchema() *Schema {
 	return nil
 }
 
+func (m *PublishRequest) GetOrder() RecordOrder {
+	if m != nil {
+		return m.Order
+	}
+	return RecordOrder_RECORD_ORDER_UNSPECIFIED
+}
+
 type PublishRecord struct {
 	// This should be set to true if the record is not valid
 	// because it violates the inferred schema in some way.
@@ -316,20 +709,41 @@ type PublishRecord struct {
 	Invalid bool `protobuf:"varint,1,opt,name=invalid,proto3" json:"invalid,omitempty"`
 	// If the record is invalid this field should explain why.
 	// This should include the property name and the original value.
+	// This is a user-directed (as opposed to machine-readable) field,
+	// so you can format it however you want.
 	Error string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	// Data contains the values for a single record.
 	// The values should be provided as a JSON serialized array.
-	Data                 string   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	// For example, if the CSV has a row
+	// 17,Alabama,true
+	// then the data field should contain the string
+	// "[17,\"Alabama\",true]"
+	// however, if you have not inferred the types of the properties,
+	// it's OK to make everything a string, which would be
+	// "[\"17\",\"Alabama\",\"true\"]"
+	Data string `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	// If the record is invalid this field should contain one entry
+	// for each property which could not be parsed. Unlike `error`
+	// these are machine-readable, so the host can check which
+	// properties were rejected and why.
+	Errors []*RecordError `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	// Plugins may periodically send a message with only progress set
+	// to let the host know how far along the publish is. Messages
+	// with progress set are not records, and their other fields are ignored.
+	Progress *PublishProgress `protobuf:"bytes,5,opt,name=progress,proto3" json:"progress,omitempty"`
+	// Where the record came from. This is optional, but if it's set
+	// the host uses it to check that records arrive in the order it asked for.
+	Provenance           *Provenance `protobuf:"bytes,6,opt,name=provenance,proto3" json:"provenance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *PublishRecord) Reset()         { *m = PublishRecord{} }
 func (m *PublishRecord) String() string { return proto.CompactTextString(m) }
 func (*PublishRecord) ProtoMessage()    {}
 func (*PublishRecord) Descriptor() ([]byte, []int) {
-	return fileDescriptor_plugin_747ce3600609d2a2, []int{6}
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{9}
 }
 func (m *PublishRecord) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_PublishRecord.Unmarshal(m, b)
@@ -370,14 +784,232 @@ func (m *PublishRecord) GetData() string {
 	return ""
 }
 
+func (m *PublishRecord) GetErrors() []*RecordError {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+func (m *PublishRecord) GetProgress() *PublishProgress {
+	if m != nil {
+		return m.Progress
+	}
+	return nil
+}
+
+func (m *PublishRecord) GetProvenance() *Provenance {
+	if m != nil {
+		return m.Provenance
+	}
+	return nil
+}
+
+type Provenance struct {
+	// Path of the file the record was read from, as matched by the glob.
+	File string `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	// Line of the file the record started on; the header is line 1.
+	Line                 int64    `protobuf:"varint,2,opt,name=line,proto3" json:"line,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Provenance) Reset()         { *m = Provenance{} }
+func (m *Provenance) String() string { return proto.CompactTextString(m) }
+func (*Provenance) ProtoMessage()    {}
+func (*Provenance) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{10}
+}
+func (m *Provenance) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Provenance.Unmarshal(m, b)
+}
+func (m *Provenance) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Provenance.Marshal(b, m, deterministic)
+}
+func (dst *Provenance) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Provenance.Merge(dst, src)
+}
+func (m *Provenance) XXX_Size() int {
+	return xxx_messageInfo_Provenance.Size(m)
+}
+func (m *Provenance) XXX_DiscardUnknown() {
+	xxx_messageInfo_Provenance.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Provenance proto.InternalMessageInfo
+
+func (m *Provenance) GetFile() string {
+	if m != nil {
+		return m.File
+	}
+	return ""
+}
+
+func (m *Provenance) GetLine() int64 {
+	if m != nil {
+		return m.Line
+	}
+	return 0
+}
+
+type PublishProgress struct {
+	// Bytes read so far, across all files.
+	BytesRead int64 `protobuf:"varint,1,opt,name=bytesRead,proto3" json:"bytesRead,omitempty"`
+	// Total size of all the files being published, if known.
+	BytesTotal int64 `protobuf:"varint,2,opt,name=bytesTotal,proto3" json:"bytesTotal,omitempty"`
+	// Number of files which have been completely published.
+	FilesDone int64 `protobuf:"varint,3,opt,name=filesDone,proto3" json:"filesDone,omitempty"`
+	// Number of files being published.
+	FilesTotal           int64    `protobuf:"varint,4,opt,name=filesTotal,proto3" json:"filesTotal,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PublishProgress) Reset()         { *m = PublishProgress{} }
+func (m *PublishProgress) String() string { return proto.CompactTextString(m) }
+func (*PublishProgress) ProtoMessage()    {}
+func (*PublishProgress) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{11}
+}
+func (m *PublishProgress) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PublishProgress.Unmarshal(m, b)
+}
+func (m *PublishProgress) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PublishProgress.Marshal(b, m, deterministic)
+}
+func (dst *PublishProgress) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PublishProgress.Merge(dst, src)
+}
+func (m *PublishProgress) XXX_Size() int {
+	return xxx_messageInfo_PublishProgress.Size(m)
+}
+func (m *PublishProgress) XXX_DiscardUnknown() {
+	xxx_messageInfo_PublishProgress.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PublishProgress proto.InternalMessageInfo
+
+func (m *PublishProgress) GetBytesRead() int64 {
+	if m != nil {
+		return m.BytesRead
+	}
+	return 0
+}
+
+func (m *PublishProgress) GetBytesTotal() int64 {
+	if m != nil {
+		return m.BytesTotal
+	}
+	return 0
+}
+
+func (m *PublishProgress) GetFilesDone() int64 {
+	if m != nil {
+		return m.FilesDone
+	}
+	return 0
+}
+
+func (m *PublishProgress) GetFilesTotal() int64 {
+	if m != nil {
+		return m.FilesTotal
+	}
+	return 0
+}
+
+type RecordError struct {
+	// Index of the invalid property in the schema's properties (and in data).
+	PropertyIndex int32 `protobuf:"varint,1,opt,name=propertyIndex,proto3" json:"propertyIndex,omitempty"`
+	// Name of the invalid property.
+	PropertyName string `protobuf:"bytes,2,opt,name=propertyName,proto3" json:"propertyName,omitempty"`
+	// The value as it appeared in the source, before any parsing.
+	OriginalValue string `protobuf:"bytes,3,opt,name=originalValue,proto3" json:"originalValue,omitempty"`
+	// The type the value was expected to have, from the schema.
+	ExpectedType string `protobuf:"bytes,4,opt,name=expectedType,proto3" json:"expectedType,omitempty"`
+	// The kind of problem with the value.
+	Code                 ErrorCode `protobuf:"varint,5,opt,name=code,proto3,enum=plugin.ErrorCode" json:"code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *RecordError) Reset()         { *m = RecordError{} }
+func (m *RecordError) String() string { return proto.CompactTextString(m) }
+func (*RecordError) ProtoMessage()    {}
+func (*RecordError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_plugin_956dd1ffb91e4a52, []int{12}
+}
+func (m *RecordError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RecordError.Unmarshal(m, b)
+}
+func (m *RecordError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RecordError.Marshal(b, m, deterministic)
+}
+func (dst *RecordError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RecordError.Merge(dst, src)
+}
+func (m *RecordError) XXX_Size() int {
+	return xxx_messageInfo_RecordError.Size(m)
+}
+func (m *RecordError) XXX_DiscardUnknown() {
+	xxx_messageInfo_RecordError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RecordError proto.InternalMessageInfo
+
+func (m *RecordError) GetPropertyIndex() int32 {
+	if m != nil {
+		return m.PropertyIndex
+	}
+	return 0
+}
+
+func (m *RecordError) GetPropertyName() string {
+	if m != nil {
+		return m.PropertyName
+	}
+	return ""
+}
+
+func (m *RecordError) GetOriginalValue() string {
+	if m != nil {
+		return m.OriginalValue
+	}
+	return ""
+}
+
+func (m *RecordError) GetExpectedType() string {
+	if m != nil {
+		return m.ExpectedType
+	}
+	return ""
+}
+
+func (m *RecordError) GetCode() ErrorCode {
+	if m != nil {
+		return m.Code
+	}
+	return ErrorCode_ERROR_CODE_UNKNOWN
+}
+
 func init() {
 	proto.RegisterType((*DiscoverRequest)(nil), "plugin.DiscoverRequest")
 	proto.RegisterType((*Settings)(nil), "plugin.Settings")
 	proto.RegisterType((*DiscoverResponse)(nil), "plugin.DiscoverResponse")
+	proto.RegisterType((*DiscoverEvent)(nil), "plugin.DiscoverEvent")
+	proto.RegisterType((*DiscoverProgress)(nil), "plugin.DiscoverProgress")
 	proto.RegisterType((*Schema)(nil), "plugin.Schema")
 	proto.RegisterType((*Property)(nil), "plugin.Property")
+	proto.RegisterType((*PropertyStats)(nil), "plugin.PropertyStats")
 	proto.RegisterType((*PublishRequest)(nil), "plugin.PublishRequest")
 	proto.RegisterType((*PublishRecord)(nil), "plugin.PublishRecord")
+	proto.RegisterType((*Provenance)(nil), "plugin.Provenance")
+	proto.RegisterType((*PublishProgress)(nil), "plugin.PublishProgress")
+	proto.RegisterType((*RecordError)(nil), "plugin.RecordError")
+	proto.RegisterEnum("plugin.RecordOrder", RecordOrder_name, RecordOrder_value)
+	proto.RegisterEnum("plugin.ErrorCode", ErrorCode_name, ErrorCode_value)
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -392,7 +1024,20 @@ const _ = grpc.SupportPackageIsVersion4
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type PluginClient interface {
+	// The Discover method is responsible for taking the provided settings
+	// and using them to find and describe all the schemas which the settings make available.
+	// In this case, the plugin will look for CSV files which match a pattern.
 	Discover(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (*DiscoverResponse, error)
+	// The DiscoverStream method does the same work as Discover, but streams
+	// back progress while it works and each schema as soon as it is found.
+	// This is useful when the settings match a very large number of files.
+	// Implementing it is optional; if it isn't implemented the host will use Discover.
+	DiscoverStream(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (Plugin_DiscoverStreamClient, error)
+	// The Publish method is responsible for collecting all the records
+	// which belong to a single schema and streaming them back to the host.
+	// The schema which is passed in will be one of the schemas returned by the
+	// Discover method, so you can share data between Discover and Publish by
+	// means of the `settings` string on the Schema message.
 	Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (Plugin_PublishClient, error)
 }
 
@@ -413,8 +1058,40 @@ func (c *pluginClient) Discover(ctx context.Context, in *DiscoverRequest, opts .
 	return out, nil
 }
 
+func (c *pluginClient) DiscoverStream(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (Plugin_DiscoverStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Plugin_serviceDesc.Streams[0], "/plugin.Plugin/DiscoverStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &pluginDiscoverStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Plugin_DiscoverStreamClient interface {
+	Recv() (*DiscoverEvent, error)
+	grpc.ClientStream
+}
+
+type pluginDiscoverStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *pluginDiscoverStreamClient) Recv() (*DiscoverEvent, error) {
+	m := new(DiscoverEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *pluginClient) Publish(ctx context.Context, in *PublishRequest, opts ...grpc.CallOption) (Plugin_PublishClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_Plugin_serviceDesc.Streams[0], "/plugin.Plugin/Publish", opts...)
+	stream, err := c.cc.NewStream(ctx, &_Plugin_serviceDesc.Streams[1], "/plugin.Plugin/Publish", opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -447,7 +1124,20 @@ func (x *pluginPublishClient) Recv() (*PublishRecord, error) {
 
 // PluginServer is the server API for Plugin service.
 type PluginServer interface {
+	// The Discover method is responsible for taking the provided settings
+	// and using them to find and describe all the schemas which the settings make available.
+	// In this case, the plugin will look for CSV files which match a pattern.
 	Discover(context.Context, *DiscoverRequest) (*DiscoverResponse, error)
+	// The DiscoverStream method does the same work as Discover, but streams
+	// back progress while it works and each schema as soon as it is found.
+	// This is useful when the settings match a very large number of files.
+	// Implementing it is optional; if it isn't implemented the host will use Discover.
+	DiscoverStream(*DiscoverRequest, Plugin_DiscoverStreamServer) error
+	// The Publish method is responsible for collecting all the records
+	// which belong to a single schema and streaming them back to the host.
+	// The schema which is passed in will be one of the schemas returned by the
+	// Discover method, so you can share data between Discover and Publish by
+	// means of the `settings` string on the Schema message.
 	Publish(*PublishRequest, Plugin_PublishServer) error
 }
 
@@ -473,6 +1163,27 @@ func _Plugin_Discover_Handler(srv interface{}, ctx context.Context, dec func(int
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Plugin_DiscoverStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DiscoverRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(PluginServer).DiscoverStream(m, &pluginDiscoverStreamServer{stream})
+}
+
+type Plugin_DiscoverStreamServer interface {
+	Send(*DiscoverEvent) error
+	grpc.ServerStream
+}
+
+type pluginDiscoverStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *pluginDiscoverStreamServer) Send(m *DiscoverEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Plugin_Publish_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(PublishRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -504,6 +1215,11 @@ var _Plugin_serviceDesc = grpc.ServiceDesc{
 		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "DiscoverStream",
+			Handler:       _Plugin_DiscoverStream_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Publish",
 			Handler:       _Plugin_Publish_Handler,
@@ -513,30 +1229,67 @@ var _Plugin_serviceDesc = grpc.ServiceDesc{
 	Metadata: "plugin.proto",
 }
 
-func init() { proto.RegisterFile("plugin.proto", fileDescriptor_plugin_747ce3600609d2a2) }
-
-var fileDescriptor_plugin_747ce3600609d2a2 = []byte{
-	// 337 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x92, 0xcb, 0x6e, 0xf2, 0x30,
-	0x10, 0x85, 0xc9, 0x0f, 0x7f, 0x48, 0x87, 0x96, 0xa2, 0x51, 0x2f, 0x11, 0xab, 0xca, 0x0b, 0xc4,
-	0xa2, 0x42, 0x28, 0xdd, 0x22, 0xb1, 0xa9, 0xd4, 0x2d, 0x0a, 0x4f, 0x10, 0xc0, 0x80, 0xab, 0x10,
-	0xbb, 0xb6, 0x41, 0xe2, 0x09, 0xfa, 0xda, 0x55, 0x7c, 0xeb, 0x25, 0x5d, 0x75, 0xe7, 0x73, 0xc6,
-	0xf6, 0x77, 0x66, 0x6c, 0xb8, 0x14, 0xe5, 0x71, 0xc7, 0xaa, 0x89, 0x90, 0x5c, 0x73, 0x8c, 0xad,
-	0x22, 0x73, 0xb8, 0x7e, 0x66, 0x6a, 0xcd, 0x4f, 0x54, 0xe6, 0xf4, 0xed, 0x48, 0x95, 0xc6, 0x47,
-	0x48, 0x14, 0xd5, 0x9a, 0x55, 0x3b, 0x95, 0x46, 0x0f, 0xd1, 0xb8, 0x97, 0x0d, 0x26, 0xee, 0xec,
-	0xd2, 0xf9, 0x79, 0xd8, 0x41, 0x46, 0x90, 0x78, 0x17, 0x87, 0x90, 0x6c, 0x59, 0x49, 0x5f, 0x4a,
-	0xbe, 0x32, 0x27, 0x2f, 0xf2, 0xa0, 0xc9, 0x0c, 0x06, 0x9f, 0x20, 0x25, 0x78, 0xa5, 0x28, 0x8e,
-	0xa1, 0xab, 0xd6, 0x7b, 0x7a, 0x28, 0x6a, 0x50, 0x7b, 0xdc, 0xcb, 0xfa, 0x01, 0x64, 0xec, 0xdc,
-	0x97, 0xc9, 0x2b, 0xc4, 0xd6, 0x42, 0x84, 0x4e, 0x55, 0x1c, 0xa8, 0xbb, 0xdf, 0xac, 0x6b, 0x6e,
-	0x48, 0xfc, 0xcf, 0x72, 0xbd, 0xc6, 0x29, 0x80, 0x90, 0x5c, 0x50, 0xa9, 0x19, 0x55, 0x69, 0xdb,
-	0x60, 0x42, 0x3f, 0x0b, 0x5b, 0x39, 0xe7, 0x5f, 0xf6, 0x90, 0x0c, 0x12, 0xef, 0xff, 0x4a, 0x43,
-	0xe8, 0xe8, 0xb3, 0xa0, 0x8e, 0x64, 0xd6, 0x64, 0x0b, 0xfd, 0xc5, 0x71, 0x55, 0x32, 0xb5, 0xff,
-	0xd3, 0x14, 0x71, 0x04, 0xb1, 0x6d, 0xd5, 0xdc, 0xda, 0x1c, 0x84, 0xab, 0x92, 0x25, 0x5c, 0x05,
-	0xce, 0x9a, 0xcb, 0x0d, 0xa6, 0xd0, 0x65, 0xd5, 0xa9, 0x28, 0xd9, 0xc6, 0x50, 0x92, 0xdc, 0x4b,
-	0xbc, 0x81, 0xff, 0x54, 0x4a, 0x2e, 0x5d, 0x4e, 0x2b, 0xea, 0xf0, 0x9b, 0x42, 0x17, 0x69, 0xdb,
-	0x86, 0xaf, 0xd7, 0xd9, 0x7b, 0x04, 0xf1, 0xc2, 0xe0, 0x70, 0x0e, 0x89, 0x7f, 0x25, 0xbc, 0xf7,
-	0x19, 0x7e, 0x7c, 0x90, 0x61, 0xda, 0x2c, 0xd8, 0x07, 0x25, 0x2d, 0x9c, 0x41, 0xd7, 0x05, 0xc4,
-	0xbb, 0x30, 0xe5, 0x6f, 0x93, 0x19, 0xde, 0x36, 0xfc, 0xba, 0x13, 0xd2, 0x9a, 0x46, 0xab, 0xd8,
-	0x7c, 0xce, 0xa7, 0x8f, 0x00, 0x00, 0x00, 0xff, 0xff, 0x35, 0xb1, 0xc4, 0x50, 0xac, 0x02, 0x00,
-	0x00,
+func init() { proto.RegisterFile("plugin.proto", fileDescriptor_plugin_956dd1ffb91e4a52) }
+
+var fileDescriptor_plugin_956dd1ffb91e4a52 = []byte{
+	// 939 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x56, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x16, 0x4d, 0xfd, 0x8e, 0x62, 0x85, 0x99, 0xd6, 0x36, 0xe1, 0x06, 0x85, 0x40, 0xb4, 0x86,
+	0xea, 0x14, 0x41, 0xa0, 0x14, 0x3d, 0x05, 0x08, 0x1c, 0x89, 0x4e, 0x84, 0xb8, 0x92, 0xb0, 0xb2,
+	0xdd, 0xf6, 0x24, 0xd0, 0xe2, 0xc6, 0x61, 0x41, 0x91, 0x2c, 0x77, 0xe5, 0x5a, 0x7e, 0x87, 0x1e,
+	0xfa, 0x56, 0xbd, 0xf4, 0x31, 0x7a, 0xe8, 0x5b, 0x14, 0xbb, 0xdc, 0xa5, 0x49, 0xba, 0xed, 0xa1,
+	0xb7, 0x9d, 0xef, 0x9b, 0xdd, 0x99, 0x9d, 0xf9, 0x66, 0x49, 0x78, 0x94, 0x84, 0x9b, 0xeb, 0x20,
+	0x7a, 0x9e, 0xa4, 0x31, 0x8f, 0xb1, 0x99, 0x59, 0xce, 0x6b, 0x78, 0x3c, 0x0e, 0xd8, 0x2a, 0xbe,
+	0xa1, 0x29, 0xa1, 0x3f, 0x6f, 0x28, 0xe3, 0xf8, 0x35, 0xb4, 0x19, 0xe5, 0x3c, 0x88, 0xae, 0x99,
+	0x6d, 0xf4, 0x8d, 0x41, 0x77, 0x68, 0x3d, 0x57, 0x7b, 0x17, 0x0a, 0x27, 0xb9, 0x87, 0x73, 0x04,
+	0x6d, 0x8d, 0xe2, 0x21, 0xb4, 0x3f, 0x04, 0x21, 0x7d, 0x1b, 0xc6, 0x57, 0x72, 0x67, 0x87, 0xe4,
+	0xb6, 0xf3, 0x0a, 0xac, 0xfb, 0x40, 0x2c, 0x89, 0x23, 0x46, 0x71, 0x00, 0x2d, 0xb6, 0xfa, 0x48,
+	0xd7, 0x9e, 0x08, 0x64, 0x0e, 0xba, 0xc3, 0x5e, 0x1e, 0x48, 0xc2, 0x44, 0xd3, 0xce, 0x1d, 0xec,
+	0xea, 0xdd, 0xee, 0x0d, 0x8d, 0x38, 0x7e, 0x0b, 0xed, 0x24, 0x8d, 0xaf, 0x53, 0xca, 0x74, 0x92,
+	0xb6, 0xde, 0xab, 0x1d, 0xe7, 0x8a, 0x7f, 0x57, 0x23, 0xb9, 0x2f, 0x0e, 0xa0, 0x99, 0x9d, 0x69,
+	0xef, 0xf4, 0x8d, 0x87, 0x11, 0xdf, 0xd5, 0x88, 0xe2, 0xdf, 0xb4, 0xa0, 0x41, 0x45, 0x28, 0x27,
+	0x05, 0xab, 0x7a, 0x24, 0x7e, 0x0e, 0x20, 0x6e, 0xc6, 0xce, 0x63, 0xee, 0x85, 0x32, 0x01, 0x93,
+	0x14, 0x10, 0x7c, 0x0a, 0x1d, 0x69, 0x8d, 0xe3, 0x88, 0xca, 0x48, 0x26, 0xb9, 0x07, 0xb0, 0x0f,
+	0xdd, 0xd5, 0x26, 0x4d, 0x69, 0xc4, 0x4f, 0x83, 0x90, 0xda, 0xa6, 0x2c, 0x55, 0x11, 0x72, 0x7e,
+	0x82, 0x66, 0x96, 0x10, 0x22, 0xd4, 0x23, 0x6f, 0x4d, 0x55, 0x3d, 0xe5, 0x5a, 0xd4, 0x39, 0xef,
+	0xd0, 0x4e, 0x56, 0x67, 0x6d, 0xe3, 0x0b, 0x80, 0x24, 0x8d, 0x13, 0x9a, 0xf2, 0x80, 0x32, 0xdb,
+	0xec, 0x9b, 0xc5, 0xfe, 0xcd, 0x33, 0x66, 0x4b, 0x0a, 0x3e, 0xce, 0x06, 0xda, 0x1a, 0xff, 0xc7,
+	0x68, 0x08, 0x75, 0xbe, 0x4d, 0xa8, 0x8a, 0x24, 0xd7, 0xf8, 0x0c, 0x1a, 0x8c, 0x7b, 0x9c, 0xc9,
+	0xdc, 0xbb, 0xc3, 0xbd, 0x6a, 0x80, 0x85, 0x20, 0x49, 0xe6, 0x83, 0x9f, 0x42, 0x23, 0x60, 0xef,
+	0xe9, 0xd6, 0xae, 0xf7, 0x8d, 0x41, 0x9b, 0x64, 0x86, 0xf3, 0xa7, 0x01, 0xbb, 0x25, 0x77, 0x71,
+	0xad, 0x34, 0xfe, 0x65, 0x14, 0x6f, 0x22, 0xae, 0x4a, 0x9a, 0xdb, 0xa2, 0xa0, 0xd1, 0x26, 0x0c,
+	0x33, 0x52, 0x15, 0x34, 0x07, 0xf0, 0x18, 0x2c, 0x3f, 0x60, 0x3c, 0x88, 0x56, 0xdc, 0x65, 0x3c,
+	0x58, 0x7b, 0x3c, 0xab, 0xaa, 0x49, 0x1e, 0xe0, 0x68, 0x81, 0xb9, 0x0e, 0x22, 0x99, 0x4b, 0x87,
+	0x88, 0xa5, 0x44, 0xbc, 0x5b, 0xbb, 0xa1, 0x10, 0xef, 0x16, 0x1d, 0x78, 0xc4, 0xbc, 0x75, 0x12,
+	0xd2, 0x4b, 0x2f, 0xdc, 0x50, 0x66, 0x37, 0xfb, 0xe6, 0xa0, 0x43, 0x4a, 0x18, 0x1e, 0x41, 0x4f,
+	0x94, 0x62, 0x14, 0x47, 0x1f, 0x02, 0x9f, 0x46, 0x2b, 0x6a, 0xb7, 0xfa, 0xc6, 0xc0, 0x20, 0x15,
+	0xd4, 0xf9, 0xcd, 0x80, 0xde, 0x7c, 0x73, 0x15, 0x06, 0xec, 0xe3, 0xff, 0x9a, 0x30, 0x3c, 0xfa,
+	0x6f, 0xc9, 0x6a, 0xc1, 0xe2, 0x57, 0xd0, 0x88, 0x53, 0x9f, 0xa6, 0xf2, 0xe6, 0xbd, 0xe1, 0x27,
+	0xda, 0x8d, 0xd0, 0x55, 0x9c, 0xfa, 0x33, 0x41, 0x91, 0xcc, 0xc3, 0xf9, 0x4b, 0xd4, 0x5e, 0xe7,
+	0x24, 0x58, 0xb4, 0xa1, 0x15, 0x44, 0x37, 0x5e, 0x18, 0xf8, 0x32, 0xa3, 0x36, 0xd1, 0xa6, 0xe8,
+	0x1e, 0x4d, 0xd3, 0x38, 0x55, 0xfd, 0xcf, 0x0c, 0x21, 0x0a, 0xdf, 0xe3, 0x9e, 0xd2, 0xae, 0x5c,
+	0xe3, 0x33, 0x68, 0x4a, 0x92, 0xd9, 0x75, 0x29, 0xbb, 0x4a, 0x06, 0xae, 0xe0, 0x88, 0x72, 0xc1,
+	0x97, 0x85, 0x01, 0x6e, 0xc8, 0x7b, 0x1d, 0xe4, 0x22, 0xca, 0x32, 0xd3, 0xc3, 0x56, 0x98, 0xde,
+	0xa1, 0x14, 0xf7, 0x0d, 0x8d, 0x3c, 0x51, 0xef, 0xa6, 0xdc, 0x86, 0x05, 0xed, 0x29, 0x86, 0x14,
+	0xbc, 0x9c, 0x6f, 0x00, 0xee, 0x19, 0x91, 0xb7, 0x98, 0x43, 0x2d, 0x70, 0xb1, 0x16, 0x58, 0x18,
+	0xe4, 0x73, 0x2a, 0xd7, 0xce, 0xaf, 0x06, 0x3c, 0xae, 0xe4, 0x21, 0x34, 0x78, 0xb5, 0xe5, 0x94,
+	0x11, 0xea, 0xf9, 0x4a, 0xa0, 0xf7, 0x80, 0x78, 0x12, 0xa4, 0x91, 0x3d, 0x09, 0xd9, 0x59, 0x05,
+	0xa4, 0xfc, 0x24, 0x98, 0xd5, 0x27, 0xa1, 0xfc, 0xa0, 0xd4, 0xab, 0x0f, 0x8a, 0xf3, 0xbb, 0x01,
+	0xdd, 0x42, 0x19, 0xf1, 0x0b, 0xd8, 0x55, 0x23, 0xbc, 0x9d, 0x44, 0x3e, 0xbd, 0x95, 0xf9, 0x34,
+	0x48, 0x19, 0x14, 0x3a, 0xd6, 0xc0, 0x54, 0x8c, 0x75, 0xd6, 0xc2, 0x12, 0x26, 0x4e, 0x8a, 0xd3,
+	0xe0, 0x3a, 0x88, 0xbc, 0x50, 0x2a, 0x5b, 0xb5, 0xb4, 0x0c, 0x8a, 0x93, 0xe8, 0x6d, 0x42, 0x57,
+	0x9c, 0xfa, 0xe7, 0xe2, 0x31, 0xc8, 0xc6, 0xa7, 0x84, 0xe1, 0x97, 0x50, 0x5f, 0xc5, 0x3e, 0x95,
+	0xed, 0xec, 0x0d, 0x9f, 0xe8, 0xbe, 0xc8, 0x84, 0x47, 0xb1, 0x4f, 0x89, 0xa4, 0x8f, 0xdf, 0x40,
+	0xb7, 0x20, 0x49, 0x7c, 0x0a, 0x36, 0x71, 0x47, 0x33, 0x32, 0x5e, 0xce, 0xc8, 0xd8, 0x25, 0xcb,
+	0x8b, 0xe9, 0x62, 0xee, 0x8e, 0x26, 0xa7, 0x13, 0x77, 0x6c, 0xd5, 0x70, 0x0f, 0x9e, 0x94, 0xd8,
+	0xd3, 0xc9, 0x99, 0x6b, 0x19, 0xc7, 0x77, 0xd0, 0xc9, 0x8f, 0xc5, 0x7d, 0x40, 0x97, 0x90, 0x19,
+	0x59, 0x8e, 0x66, 0x63, 0x77, 0x79, 0x31, 0x7d, 0x3f, 0x9d, 0x7d, 0x3f, 0xb5, 0x6a, 0xf8, 0x19,
+	0x1c, 0x14, 0xf0, 0xc9, 0xf4, 0xf2, 0xe4, 0x6c, 0x32, 0x5e, 0x9e, 0xff, 0x38, 0x77, 0x2d, 0x43,
+	0x84, 0x2d, 0x90, 0xdf, 0x4d, 0x16, 0x8b, 0xc9, 0xf4, 0xed, 0xf2, 0xf2, 0xe4, 0xec, 0xc2, 0xb5,
+	0x76, 0xf0, 0x10, 0xf6, 0x0b, 0xac, 0xfb, 0xc3, 0x39, 0x39, 0x51, 0x9c, 0x39, 0xfc, 0xc3, 0x80,
+	0xe6, 0x5c, 0x5e, 0x0d, 0x5f, 0x43, 0x5b, 0x7f, 0x1a, 0xf0, 0xa0, 0xfa, 0xfd, 0x51, 0xd3, 0x7e,
+	0x68, 0x3f, 0x24, 0xb2, 0xef, 0x9f, 0x53, 0xc3, 0x31, 0xf4, 0x34, 0xba, 0xe0, 0x29, 0xf5, 0xd6,
+	0xff, 0x7e, 0xcc, 0x5e, 0x95, 0x90, 0x1f, 0x42, 0xa7, 0xf6, 0xc2, 0xc0, 0x57, 0xd0, 0x52, 0x5a,
+	0xc5, 0xfd, 0xca, 0x10, 0x3d, 0xd8, 0x5d, 0x1a, 0x7b, 0xb1, 0xfb, 0xaa, 0x29, 0xff, 0x08, 0x5e,
+	0xfe, 0x3d, 0x00, 0xd4, 0x51, 0x6d, 0xc2, 0x21, 0x08, 0x00, 0x00,
 }
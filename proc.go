@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// procSample is a measurement of a process (and its descendants) taken from /proc.
+// This only works on Linux; on other platforms readProcSample returns an error.
+type procSample struct {
+	// rss is the resident set size in bytes.
+	rss int64
+	// cpu is the total user and system CPU time used.
+	cpu time.Duration
+	// readBytes is the number of bytes read by read syscalls, from files or sockets.
+	readBytes int64
+	// fds is the number of open file descriptors.
+	fds int
+	// threads is the number of threads.
+	threads int
+}
+
+// clockTicks is the unit of the CPU times in /proc/[pid]/stat. It is almost
+// always 100 on Linux, and reading the real value would need cgo.
+const clockTicks = 100
+
+// readProcSample measures the process pid and all of its descendants,
+// so plugins started through a shell script are measured correctly.
+func readProcSample(pid int) (procSample, error) {
+	var sample procSample
+	pids, err := processTree(pid)
+	if err != nil {
+		return sample, err
+	}
+	for _, p := range pids {
+		// Any of these may fail if the process has exited since we listed it.
+		if kb, err := readStatus(p, "VmRSS"); err == nil {
+			sample.rss += kb * 1024
+		}
+		if n, err := readStatus(p, "Threads"); err == nil {
+			sample.threads += int(n)
+		}
+		if n, err := readFDs(p); err == nil {
+			sample.fds += n
+		}
+		if cpu, err := readCPU(p); err == nil {
+			sample.cpu += cpu
+		}
+		if n, err := readIO(p, "rchar"); err == nil {
+			sample.readBytes += n
+		}
+	}
+	return sample, nil
+}
+
+// readCPU returns the user and system CPU time used by pid.
+func readCPU(pid int) (time.Duration, error) {
+	fields, err := readStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) < 13 {
+		return 0, errors.Errorf("bad stat for process %d", pid)
+	}
+	var ticks int64
+	for _, f := range fields[11:13] {
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "bad cpu time")
+		}
+		ticks += n
+	}
+	return time.Duration(ticks) * time.Second / clockTicks, nil
+}
+
+// readIO returns a counter from /proc/[pid]/io.
+func readIO(pid int, name string) (int64, error) {
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "io"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == name+":" {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	return 0, errors.Errorf("no %s for process %d", name, pid)
+}
+
+// readStatus returns a number from /proc/[pid]/status, like VmRSS (in kB) or Threads.
+func readStatus(pid int, name string) (int64, error) {
+	f, err := os.Open(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == name+":" {
+			n, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return 0, errors.Wrap(err, "bad "+name)
+			}
+			return n, nil
+		}
+	}
+	return 0, errors.Errorf("no %s for process %d", name, pid)
+}
+
+// readFDs returns the number of file descriptors pid has open.
+func readFDs(pid int) (int, error) {
+	fds, err := ioutil.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "fd"))
+	if err != nil {
+		return 0, err
+	}
+	return len(fds), nil
+}
+
+// processTree returns pid and the IDs of all its descendants.
+func processTree(pid int) ([]int, error) {
+	if _, err := os.Stat(filepath.Join("/proc", strconv.Itoa(pid))); err != nil {
+		return nil, errors.Wrapf(err, "process %d not found in /proc", pid)
+	}
+
+	children := map[int][]int{}
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		ppid, err := readPPID(p)
+		if err != nil {
+			continue
+		}
+		children[ppid] = append(children[ppid], p)
+	}
+
+	tree := []int{pid}
+	for i := 0; i < len(tree); i++ {
+		tree = append(tree, children[tree[i]]...)
+	}
+	return tree, nil
+}
+
+// processGroup returns the IDs of the running processes in process group pgid.
+func processGroup(pgid int) []int {
+	entries, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var group []int
+	for _, e := range entries {
+		p, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+		fields, err := readStat(p)
+		if err != nil || len(fields) < 3 || fields[0] == "Z" {
+			continue
+		}
+		if fields[2] == strconv.Itoa(pgid) {
+			group = append(group, p)
+		}
+	}
+	return group
+}
+
+// readPPID returns the parent process ID of pid.
+func readPPID(pid int) (int, error) {
+	fields, err := readStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) < 2 {
+		return 0, errors.Errorf("bad stat for process %d", pid)
+	}
+	return strconv.Atoi(fields[1])
+}
+
+// readStat returns the fields of /proc/[pid]/stat which follow the command name,
+// so the first field is the process state and the second is the parent process ID.
+func readStat(pid int) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	// The second field is the command name in parentheses, which may
+	// contain spaces, so the fields we want start after the last ')'.
+	stat := string(b)
+	return strings.Fields(stat[strings.LastIndex(stat, ")")+1:]), nil
+}
+
+// processAlive reports whether pid is still running. Zombies,
+// which have exited but not been waited for, are not running.
+func processAlive(pid int) bool {
+	fields, err := readStat(pid)
+	return err == nil && len(fields) > 0 && fields[0] != "Z"
+}
+
+// processCommand returns the command line of pid, for reporting.
+func processCommand(pid int) string {
+	b, err := ioutil.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil || len(b) == 0 {
+		return "?"
+	}
+	return strings.TrimSpace(strings.Replace(string(b), "\x00", " ", -1))
+}
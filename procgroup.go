@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// kill kills the plugin's whole process group, so that anything the plugin
+// started (like the real server, when the plugin is a script) dies with it.
+func (p *pluginProcess) kill() {
+	p.signal(syscall.SIGKILL)
+}
+
+// stop asks the plugin to exit with SIGINT and waits for it, killing it if
+// it takes longer than pluginStopTimeout. Then it reports and kills anything
+// the plugin left running.
+func (p *pluginProcess) stop() {
+	select {
+	case <-p.exited:
+	default:
+		p.expectExit()
+		log.Printf("stopping plugin...")
+		// Like pressing Ctrl-C, this signals the whole process group.
+		p.signal(syscall.SIGINT)
+		select {
+		case <-p.exited:
+		case <-time.After(pluginStopTimeout):
+			log.Printf("%s", color.YellowString("plugin did not exit within %s of SIGINT, killing it", pluginStopTimeout))
+			p.kill()
+			<-p.exited
+		}
+	}
+
+	if orphans := p.killOrphans(nil); len(orphans) > 0 {
+		log.Printf("%s", color.RedString("plugin left %d processes running after it exited, killed them: %s", len(orphans), strings.Join(orphans, ", ")))
+	}
+}
+
+// killOrphans finds the processes which are still running after the plugin
+// exited, from its process group and from known (the plugin's descendants,
+// which may have left its process group). It kills them and returns
+// descriptions of them for reporting.
+func (p *pluginProcess) killOrphans(known []int) []string {
+	seen := map[int]bool{p.pid(): true}
+	var pids []int
+	for _, pid := range append(known, processGroup(p.pid())...) {
+		if !seen[pid] && processAlive(pid) {
+			pids = append(pids, pid)
+		}
+		seen[pid] = true
+	}
+	sort.Ints(pids)
+
+	var orphans []string
+	for _, pid := range pids {
+		orphans = append(orphans, fmt.Sprintf("%d (%s)", pid, processCommand(pid)))
+		killProcess(pid)
+	}
+	p.kill()
+	return orphans
+}
@@ -0,0 +1,25 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes cmd start in its own process group, so that if the
+// plugin is started by a script, the processes the script starts can be
+// signalled and killed along with it.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// signal sends sig to the plugin's whole process group.
+func (p *pluginProcess) signal(sig syscall.Signal) error {
+	return syscall.Kill(-p.pid(), sig)
+}
+
+func killProcess(pid int) {
+	syscall.Kill(pid, syscall.SIGKILL)
+}
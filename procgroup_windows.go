@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup does nothing on Windows, which has no process groups to
+// signal, so only the plugin itself is stopped.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// signal sends sig to the plugin. Windows can't deliver signals other than
+// kill, so the plugin is killed if sending sig fails.
+func (p *pluginProcess) signal(sig syscall.Signal) error {
+	if err := p.cmd.Process.Signal(sig); err != nil {
+		return p.cmd.Process.Kill()
+	}
+	return nil
+}
+
+func killProcess(pid int) {
+	if proc, err := os.FindProcess(pid); err == nil {
+		proc.Kill()
+	}
+}
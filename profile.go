@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// parseProfileArgs parses the arguments to the profile command,
+// returning the remaining arguments (the plugin command) and
+// the command which renders the profile.
+func parseProfileArgs(args []string) ([]string, command) {
+	pwd, _ := os.Getwd()
+	fs := flag.NewFlagSet("profile", flag.ExitOnError)
+	glob := fs.String("glob", filepath.Join(pwd, "data", "*.csv"), "file glob to discover schemas from")
+	fs.Parse(args)
+
+	return fs.Args(), func(client plugin.PluginClient) error {
+		return runProfile(client, *glob)
+	}
+}
+
+// runProfile discovers the schemas matching glob and renders
+// the statistics the plugin reported for each property.
+func runProfile(client plugin.PluginClient, glob string) error {
+	log.Printf("profiling %s", glob)
+
+	discover, err := discoverSchemas(client, &plugin.Settings{
+		FileGlob: glob,
+	}, discoverTimeout, log.Printf)
+	if err != nil {
+		err = errors.WithMessage(err, "discovery failed")
+		log.Print(color.RedString(err.Error()))
+		return err
+	}
+
+	for _, schema := range discover.Schemas {
+		renderProfile(schema)
+	}
+
+	return nil
+}
+
+func renderProfile(schema *plugin.Schema) {
+	color.Blue("SCHEMA %s", schema.Name)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  property\ttype\tconfidence\trows\tnulls\tdistinct\tmin\tmax\tsamples")
+	for _, p := range schema.Properties {
+		s := p.Stats
+		if s == nil {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", p.Name, p.Type, color.YellowString("no statistics reported"))
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t%s\n",
+			p.Name,
+			p.Type,
+			formatConfidence(s.TypeConfidence),
+			s.RowCount,
+			s.NullCount,
+			s.DistinctEstimate,
+			truncate(s.Min, 24),
+			truncate(s.Max, 24),
+			formatSamples(s.SampleValues),
+		)
+	}
+	w.Flush()
+	fmt.Println()
+}
+
+func formatConfidence(c float64) string {
+	text := fmt.Sprintf("%5.1f%%", c*100)
+	switch {
+	case c >= 0.99:
+		return color.GreenString(text)
+	case c >= 0.9:
+		return color.YellowString(text)
+	default:
+		return color.RedString(text)
+	}
+}
+
+func formatSamples(samples []string) string {
+	var quoted []string
+	for _, s := range samples {
+		quoted = append(quoted, fmt.Sprintf("%q", truncate(s, 16)))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func truncate(s string, max int) string {
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-1]) + "…"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"os"
+	"strings"
+	"time"
+)
+
+// progressInterval limits how often publish progress is rendered.
+var progressInterval = 100 * time.Millisecond
+
+const progressBarWidth = 30
+
+// progressBar renders the progress messages a plugin sends during Publish.
+// On a terminal the bar is redrawn in place; otherwise (or when tests are
+// running in parallel and would draw over each other) it is logged periodically.
+type progressBar struct {
+	name     string
+	last     *plugin.PublishProgress
+	count    int
+	rendered time.Time
+	drawn    bool
+}
+
+func newProgressBar(name string) *progressBar {
+	return &progressBar{name: name}
+}
+
+// update records the latest progress and the number of records received so far,
+// and renders the bar if it hasn't been rendered recently.
+func (b *progressBar) update(progress *plugin.PublishProgress, count int) {
+	b.last = progress
+	b.count = count
+	if time.Since(b.rendered) < progressInterval {
+		return
+	}
+	b.render()
+}
+
+// finish renders the final state of the bar, if the plugin ever reported progress.
+func (b *progressBar) finish(count int) {
+	if b.last == nil {
+		return
+	}
+	b.count = count
+	b.render()
+	if b.drawn {
+		fmt.Fprintln(os.Stdout)
+	}
+}
+
+func (b *progressBar) render() {
+	b.rendered = time.Now()
+	line := b.String()
+	if color.NoColor || parallel > 1 {
+		log.Printf(color.CyanString(b.name+": ")+"publish progress: %s", line)
+		return
+	}
+	b.drawn = true
+	fmt.Fprintf(os.Stdout, "\r%s %s", color.CyanString(b.name+":"), line)
+}
+
+func (b *progressBar) String() string {
+	p := b.last
+	w := new(strings.Builder)
+
+	if p.BytesTotal > 0 {
+		fraction := float64(p.BytesRead) / float64(p.BytesTotal)
+		if fraction > 1 {
+			fraction = 1
+		}
+		filled := int(fraction * progressBarWidth)
+		fmt.Fprintf(w, "[%s%s] %3.0f%% %s/%s",
+			strings.Repeat("#", filled),
+			strings.Repeat(".", progressBarWidth-filled),
+			fraction*100,
+			formatBytes(p.BytesRead),
+			formatBytes(p.BytesTotal))
+	} else {
+		fmt.Fprintf(w, "%s read", formatBytes(p.BytesRead))
+	}
+
+	if p.FilesTotal > 0 {
+		fmt.Fprintf(w, ", files %d/%d", p.FilesDone, p.FilesTotal)
+	}
+	fmt.Fprintf(w, ", %d records", b.count)
+
+	return w.String()
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"sort"
+	"strings"
+)
+
+var (
+	repeatRuns  int
+	repeatOrder bool
+)
+
+func init() {
+	flag.IntVar(&repeatRuns, "repeat", 1, "discover and publish each test this many times, checking that the output is the same every time")
+	flag.BoolVar(&repeatOrder, "repeat-order", false, "with -repeat, also require the records to be published in the same order every time")
+}
+
+// flake records a way the output of a test changed between runs. Changes
+// which matter fail the test; the others are only reported.
+func (t *testResult) flake(fail bool, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	t.flaky = append(t.flaky, msg)
+	if fail {
+		t.flakyErr = true
+		t.comment("%s", color.RedString("%s", msg))
+	} else {
+		t.comment("%s", color.YellowString("%s", msg))
+	}
+}
+
+// runOutput is the output of one run of a test with -repeat.
+type runOutput struct {
+	run      int
+	discover *plugin.DiscoverResponse
+	// records are normalized, in the order they arrived; published
+	// is set if the publish completed.
+	records   []string
+	published bool
+}
+
+// executeRepeats runs the test -repeat times, then compares the output of the
+// runs with each other. Only the first run's comments are reported; a test which
+// fails on some runs but not others is reported as failing intermittently.
+func (t *standardTestCase) executeRepeats(client plugin.PluginClient, result *testResult) *testResult {
+	var outputs []*runOutput
+	var failed []int
+	var firstErr error
+	for run := 1; run <= repeatRuns; run++ {
+		result.log("run %d/%d", run, repeatRuns)
+		out := &runOutput{run: run}
+		runResult := result
+		if run > 1 {
+			runResult = &testResult{test: result.test, flog: result.flog}
+		}
+		t.fresh().executeRun(client, runResult, out)
+		outputs = append(outputs, out)
+		if runResult.err != nil {
+			result.log("run %d/%d failed: %s", run, repeatRuns, runResult.err)
+			if len(failed) == 0 {
+				firstErr = runResult.err
+			}
+			failed = append(failed, run)
+		}
+	}
+
+	if len(failed) > 0 && len(failed) < repeatRuns {
+		result.flake(true, "fails intermittently (%d/%d): run %d failed: %s", len(failed), repeatRuns, failed[0], firstErr)
+	}
+	compareRuns(result, outputs)
+
+	// A failure on the first run is reported as it is.
+	if result.err != nil {
+		return result
+	}
+	if len(failed) > 0 {
+		return result.withErr(errors.Errorf("failed on %d of %d runs, first on run %d: %s", len(failed), repeatRuns, failed[0], firstErr))
+	}
+	if result.flakyErr {
+		return result.withErr(errors.Errorf("output changed between the %d runs", repeatRuns))
+	}
+	result.comment("%s", color.GreenString("output was the same in all %d runs", repeatRuns))
+	return result
+}
+
+// fresh returns a copy of the test which hasn't evaluated any records, to run it again.
+func (t *standardTestCase) fresh() *standardTestCase {
+	c := *t
+	c.recordChecks = nil
+	for _, r := range t.recordChecks {
+		check := *r
+		check.match, check.parseErr = nil, nil
+		c.recordChecks = append(c.recordChecks, &check)
+	}
+	c.assertions = nil
+	for _, a := range t.assertions {
+		// The text parsed when the test was loaded, so it parses again.
+		fresh, _ := parseAssertion(a.String(), t.publishSchema)
+		c.assertions = append(c.assertions, fresh)
+	}
+	return &c
+}
+
+// compareRuns compares the output of each run with the first run which got as
+// far, so runs which failed part way are compared as far as they went.
+func compareRuns(result *testResult, outputs []*runOutput) {
+	var discovered, published *runOutput
+	var publishedSorted []string
+	for _, out := range outputs {
+		if out.discover != nil {
+			if discovered == nil {
+				discovered = out
+			} else {
+				compareDiscovers(result, discovered.run, out.run, discovered.discover, out.discover)
+			}
+		}
+		if out.published {
+			if published == nil {
+				published = out
+				publishedSorted = sortedCopy(out.records)
+			} else {
+				compareRecords(result, published.run, out.run, published.records, publishedSorted, out.records)
+			}
+		}
+	}
+}
+
+// compareDiscovers compares the schemas discovered on a run with an earlier run.
+func compareDiscovers(result *testResult, firstRun, run int, first, current *plugin.DiscoverResponse) {
+	firstSchemas := schemasByName(first.Schemas)
+	currentSchemas := schemasByName(current.Schemas)
+
+	var firstNames, currentNames []string
+	for name := range firstSchemas {
+		firstNames = append(firstNames, name)
+	}
+	for name := range currentSchemas {
+		currentNames = append(currentNames, name)
+	}
+	sort.Strings(firstNames)
+	sort.Strings(currentNames)
+	if strings.Join(firstNames, ",") != strings.Join(currentNames, ",") {
+		result.flake(true, "run %d discovered schemas %s, but run %d discovered %s", run, strings.Join(currentNames, ", "), firstRun, strings.Join(firstNames, ", "))
+	}
+
+	for _, name := range firstNames {
+		a, b := firstSchemas[name], currentSchemas[name]
+		if b == nil {
+			continue
+		}
+		if a.Settings != b.Settings {
+			result.flake(false, "run %d discovered schema %s with different settings: %q, but run %d had %q", run, name, b.Settings, firstRun, a.Settings)
+		}
+		if describeProperties(a) != describeProperties(b) {
+			result.flake(true, "run %d discovered schema %s with properties %s, but run %d had %s", run, name, describeProperties(b), firstRun, describeProperties(a))
+		}
+	}
+}
+
+func describeProperties(s *plugin.Schema) string {
+	var props []string
+	for _, p := range s.Properties {
+		props = append(props, (&goldenProperty{Name: p.Name, Type: p.Type, IsKey: p.IsKey}).String())
+	}
+	return strings.Join(props, ", ")
+}
+
+// compareRecords compares the records published on a run with an earlier run.
+func compareRecords(result *testResult, firstRun, run int, first, firstSorted, current []string) {
+	if len(current) != len(first) {
+		result.flake(true, "run %d published %d records, but run %d published %d", run, len(current), firstRun, len(first))
+	}
+
+	missing, added := diffRecords(firstSorted, sortedCopy(current))
+	if len(missing) > 0 || len(added) > 0 {
+		msg := fmt.Sprintf("run %d published %d records which run %d didn't, and not %d which it did", run, len(added), firstRun, len(missing))
+		if len(added) > 0 {
+			msg += "; new: " + added[0]
+		}
+		if len(missing) > 0 {
+			msg += "; missing: " + missing[0]
+		}
+		result.flake(true, "%s", msg)
+		return
+	}
+
+	for i := range current {
+		if current[i] != first[i] {
+			msg := fmt.Sprintf("run %d published the same records as run %d in a different order, starting with record %d", run, firstRun, i+1)
+			if repeatOrder {
+				result.flake(true, "%s", msg)
+			} else {
+				result.comment("%s", color.YellowString("%s", msg))
+			}
+			return
+		}
+	}
+}
+
+func sortedCopy(s []string) []string {
+	c := append([]string(nil), s...)
+	sort.Strings(c)
+	return c
+}
+
+// printRepeatSummary lists the tests whose output changed between runs.
+func printRepeatSummary(results []*testResult) {
+	if repeatRuns < 2 {
+		return
+	}
+
+	color.Blue("DETERMINISM")
+	flaky := 0
+	for _, result := range results {
+		if len(result.flaky) == 0 {
+			continue
+		}
+		flaky++
+		if result.flakyErr {
+			fmt.Printf("  %s\n", color.RedString("%s", result.test.name()))
+		} else {
+			fmt.Printf("  %s\n", color.YellowString("%s", result.test.name()))
+		}
+		for _, f := range result.flaky {
+			fmt.Printf("    %s\n", f)
+		}
+	}
+	if flaky == 0 {
+		fmt.Printf("  %s\n", color.GreenString("no test's output changed in %d runs", repeatRuns))
+	}
+}
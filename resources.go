@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// These limits fail a test if the plugin uses more than they allow while
+// the test is running. Zero means no limit.
+var (
+	maxTestCPU     time.Duration
+	maxTestRSS     int64
+	maxTestFDs     int
+	maxTestThreads int
+)
+
+// resourceSampleInterval is how often the plugin is measured during a test.
+var resourceSampleInterval = 50 * time.Millisecond
+
+func init() {
+	flag.DurationVar(&maxTestCPU, "max-cpu", maxTestCPU, "fail a test if the plugin uses more CPU time than this while it runs (0 for no limit)")
+	flag.Int64Var(&maxTestRSS, "max-rss", maxTestRSS, "fail a test if the plugin's memory (in bytes) goes above this while it runs (0 for no limit)")
+	flag.IntVar(&maxTestFDs, "max-fds", maxTestFDs, "fail a test if the plugin has more file descriptors open than this while it runs (0 for no limit)")
+	flag.IntVar(&maxTestThreads, "max-threads", maxTestThreads, "fail a test if the plugin has more threads than this while it runs (0 for no limit)")
+}
+
+// resourceUsage is what the plugin used while a test ran. When tests run in
+// parallel, the usage of the tests running at the same time overlaps.
+type resourceUsage struct {
+	cpu         time.Duration
+	peakRSS     int64
+	peakFDs     int
+	peakThreads int
+}
+
+func (u *resourceUsage) String() string {
+	return fmt.Sprintf("cpu %s, peak memory %s, peak fds %d, peak threads %d",
+		u.cpu.Round(time.Millisecond), formatBytes(u.peakRSS), u.peakFDs, u.peakThreads)
+}
+
+// resourceMonitor samples the plugin's resource usage until it is finished.
+type resourceMonitor struct {
+	stop chan struct{}
+	done chan *resourceUsage
+}
+
+// monitorResources starts measuring p. It returns nil if p can't be measured,
+// for example because /proc isn't available.
+func monitorResources(p *pluginProcess) *resourceMonitor {
+	first, err := readProcSample(p.pid())
+	if err != nil {
+		return nil
+	}
+
+	m := &resourceMonitor{
+		stop: make(chan struct{}),
+		done: make(chan *resourceUsage, 1),
+	}
+	go func() {
+		usage := new(resourceUsage)
+		last := first
+		record := func(sample procSample) {
+			last = sample
+			if sample.rss > usage.peakRSS {
+				usage.peakRSS = sample.rss
+			}
+			if sample.fds > usage.peakFDs {
+				usage.peakFDs = sample.fds
+			}
+			if sample.threads > usage.peakThreads {
+				usage.peakThreads = sample.threads
+			}
+		}
+		record(first)
+
+		ticker := time.NewTicker(resourceSampleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.stop:
+				// The plugin may have exited, in which case the last sample is the best we have.
+				if sample, err := readProcSample(p.pid()); err == nil {
+					record(sample)
+				}
+				usage.cpu = last.cpu - first.cpu
+				m.done <- usage
+				return
+			case <-ticker.C:
+				if sample, err := readProcSample(p.pid()); err == nil {
+					record(sample)
+				}
+			}
+		}
+	}()
+	return m
+}
+
+// finish stops measuring and returns what the plugin used.
+func (m *resourceMonitor) finish() *resourceUsage {
+	close(m.stop)
+	return <-m.done
+}
+
+// checkResourceLimits fails result if usage is over any of the configured limits.
+func checkResourceLimits(result *testResult, usage *resourceUsage) {
+	var over []string
+	if maxTestCPU > 0 && usage.cpu > maxTestCPU {
+		over = append(over, fmt.Sprintf("cpu %s > %s", usage.cpu.Round(time.Millisecond), maxTestCPU))
+	}
+	if maxTestRSS > 0 && usage.peakRSS > maxTestRSS {
+		over = append(over, fmt.Sprintf("memory %s > %s", formatBytes(usage.peakRSS), formatBytes(maxTestRSS)))
+	}
+	if maxTestFDs > 0 && usage.peakFDs > maxTestFDs {
+		over = append(over, fmt.Sprintf("fds %d > %d", usage.peakFDs, maxTestFDs))
+	}
+	if maxTestThreads > 0 && usage.peakThreads > maxTestThreads {
+		over = append(over, fmt.Sprintf("threads %d > %d", usage.peakThreads, maxTestThreads))
+	}
+	if len(over) == 0 {
+		return
+	}
+
+	msg := "plugin used more than the resource limits: " + strings.Join(over, ", ")
+	result.comment("%s", color.RedString("%s", msg))
+	if result.err == nil {
+		result.err = errors.New(msg)
+	} else {
+		result.err = errors.WithMessage(result.err, msg)
+	}
+}
+
+// printResourceUsage prints a table of what the plugin used during each test.
+func printResourceUsage(results []*testResult) {
+	measured := false
+	for _, result := range results {
+		measured = measured || result.usage != nil
+	}
+	if !measured {
+		return
+	}
+
+	color.Blue("RESOURCES")
+	if parallel > 1 {
+		color.New(color.Faint, color.FgWhite).Println("  tests ran in parallel, so their usage overlaps")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  test\tcpu\tpeak memory\tpeak fds\tpeak threads")
+	for _, result := range results {
+		u := result.usage
+		if u == nil {
+			fmt.Fprintf(w, "  %s\t-\t-\t-\t-\n", result.test.name())
+			continue
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%d\t%d\n", result.test.name(), u.cpu.Round(time.Millisecond), formatBytes(u.peakRSS), u.peakFDs, u.peakThreads)
+	}
+	w.Flush()
+}
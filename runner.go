@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	golog "log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parallel is the number of tests which are executed at the same time.
+// Real hosts call plugins concurrently, so running tests in parallel
+// finds plugins which share mutable state between calls.
+var parallel = 1
+
+func init() {
+	flag.IntVar(&parallel, "parallel", parallel, "number of tests to run at the same time against the plugin")
+}
+
+// exclusiveTest is implemented by tests which measure or control the plugin
+// process itself, so they must not run at the same time as other tests.
+type exclusiveTest interface {
+	test
+	exclusive()
+}
+
+// executeTests runs the tests against client, at most parallel at a time,
+// and returns the results in the same order as the tests.
+func executeTests(client plugin.PluginClient, tests []test) []*testResult {
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > 1 {
+		log.Printf("running up to %d tests in parallel", parallel)
+	}
+
+	sup := &supervisor{plugin: activePlugin, client: client}
+	results := make([]*testResult, len(tests))
+	sem := make(chan struct{}, parallel)
+	wg := new(sync.WaitGroup)
+
+	for i, t := range tests {
+		if _, ok := t.(exclusiveTest); ok {
+			// Wait for the running tests to finish, then run this one on its own.
+			wg.Wait()
+			results[i] = executeTest(sup, t, i, len(tests))
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, t test) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = executeTest(sup, t, i, len(tests))
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results
+}
+
+func executeTest(sup *supervisor, t test, i, total int) *testResult {
+	buf := new(bytes.Buffer)
+	result := &testResult{
+		test: t,
+		flog: golog.New(buf, "", 0),
+	}
+
+	result.log("%d/%d: executing test", i+1, total)
+	result.log("description: %s", t.description())
+	result.flog.Println(strings.Repeat("-", 50))
+	result.flog.Print(t.name())
+	result.flog.Println(strings.Repeat("-", 50))
+
+	p, client := sup.current()
+	if p.hasExited() {
+		if !supervise {
+			result.withErr(errors.Errorf("not run, because the plugin exited with code %d (use -supervise to restart it)", p.exitCode))
+		} else if err := sup.restart(p); err != nil {
+			result.withErr(errors.WithMessage(err, "not run, because the plugin couldn't be restarted"))
+		} else {
+			p, client = sup.current()
+		}
+	}
+
+	if result.err == nil {
+		lines := pluginOutput.count()
+		monitor := monitorResources(p)
+		result = t.execute(client, result)
+		if monitor != nil {
+			result.usage = monitor.finish()
+			result.log("resources: %s", result.usage)
+			result.flog.Printf("resources: %s", result.usage)
+			checkResourceLimits(result, result.usage)
+		}
+		// A crash usually shows up as a failed test before the host sees the
+		// plugin exit, so only wait for the exit if the test failed.
+		var wait time.Duration
+		if result.err != nil {
+			wait = crashDetectDelay
+		}
+		if p.crashed(wait) {
+			recordCrash(result, p, lines)
+			if supervise {
+				if err := sup.restart(p); err != nil {
+					result.comment("%s", color.RedString("couldn't restart plugin: %s", err))
+				}
+			}
+		}
+	}
+	result.test = t
+	if result.err != nil {
+		result.log("%s", color.RedString("test %s failed: %s", t.name(), result.err))
+	} else {
+		result.log("%s", color.GreenString("test %s passed", t.name()))
+	}
+
+	// Each test's data is written to the log file in one piece,
+	// so tests running in parallel don't interleave their output.
+	flog.Print(buf.String())
+
+	return result
+}
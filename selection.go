@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"regexp"
+)
+
+var (
+	runPattern    string
+	skipPattern   string
+	listTests     bool
+	extendedTests bool
+)
+
+func init() {
+	flag.StringVar(&runPattern, "run", "", "only run tests whose names match this regular expression")
+	flag.StringVar(&skipPattern, "skip", "", "don't run tests whose names match this regular expression")
+	flag.BoolVar(&listTests, "list", false, "list the tests which would be run, without starting the plugin")
+	flag.BoolVar(&extendedTests, "extended", false, "also run the extended tests, which are slower and hold the plugin to stricter limits")
+}
+
+// selectTests returns the tests which match -run and don't match -skip.
+func selectTests(tests []test) []test {
+	run := compilePattern("run", runPattern)
+	skip := compilePattern("skip", skipPattern)
+
+	var selected []test
+	for _, t := range tests {
+		if run != nil && !run.MatchString(t.name()) {
+			continue
+		}
+		if skip != nil && skip.MatchString(t.name()) {
+			log.Printf("skipping test %q", t.name())
+			continue
+		}
+		selected = append(selected, t)
+	}
+	return selected
+}
+
+func compilePattern(name, pattern string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Fatalf("invalid -%s pattern %q: %s", name, pattern, err)
+	}
+	return re
+}
+
+func printTests(tests []test) {
+	for _, t := range tests {
+		fmt.Println(t.name())
+		color.New(color.Faint, color.FgWhite).Printf("  %s\n", t.description())
+	}
+}
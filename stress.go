@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// stressTestCase opens many Publish streams at the same time, for several
+// schemas and several times for the same schema, and checks that every
+// stream gets the full record set without records being lost, duplicated
+// or mixed up between streams.
+type stressTestCase struct {
+	n    string
+	d    string
+	glob string
+	// schemas are published concurrently, each streamsPerSchema times.
+	schemas          []stressSchema
+	streamsPerSchema int
+}
+
+type stressSchema struct {
+	schema        plugin.Schema
+	expectedCount int
+}
+
+func (t *stressTestCase) name() string {
+	return t.n
+}
+
+func (t *stressTestCase) description() string {
+	return t.d
+}
+
+// streamResult is what a single Publish stream received.
+type streamResult struct {
+	schema  string
+	stream  int
+	count   int
+	digest  string
+	latency time.Duration
+	err     error
+}
+
+func (t *stressTestCase) execute(client plugin.PluginClient, result *testResult) *testResult {
+	result.log("executing discover...")
+	settings := &plugin.Settings{
+		FileGlob: t.glob,
+	}
+	discover, err := discoverSchemas(client, settings, discoverTimeout, result.log)
+	if err != nil {
+		return result.withErr(errors.WithMessage(err, "discovery failed"))
+	}
+
+	var targets []*plugin.Schema
+	for _, s := range t.schemas {
+		target := findSchemaIn(s.schema, discover.Schemas)
+		if target == nil {
+			return result.withErr(errors.Errorf("no schema matching %q was discovered", s.schema.Name))
+		}
+		targets = append(targets, target)
+	}
+
+	// Publish each schema once on its own so we know what every stream should get.
+	result.log("publishing each schema alone to get the expected records...")
+	expected := map[string]*streamResult{}
+	for i, target := range targets {
+		r := publishAndDigest(client, settings, target, 0)
+		if r.err != nil {
+			return result.withErr(errors.WithMessage(r.err, "publish failed for "+target.Name))
+		}
+		if r.count != t.schemas[i].expectedCount {
+			return result.withErr(errors.Errorf("publish of %s did not return the right number of records (wanted %d, got %d)", target.Name, t.schemas[i].expectedCount, r.count))
+		}
+		expected[target.Name] = r
+	}
+
+	total := len(targets) * t.streamsPerSchema
+	result.log("opening %d concurrent publish streams...", total)
+	results := make(chan *streamResult, total)
+	wg := new(sync.WaitGroup)
+	start := make(chan struct{})
+	for _, target := range targets {
+		for i := 0; i < t.streamsPerSchema; i++ {
+			wg.Add(1)
+			go func(target *plugin.Schema, i int) {
+				defer wg.Done()
+				<-start
+				results <- publishAndDigest(client, settings, target, i)
+			}(target, i)
+		}
+	}
+	close(start)
+	wg.Wait()
+	close(results)
+
+	var latencies []time.Duration
+	var failures []string
+	for r := range results {
+		latencies = append(latencies, r.latency)
+		want := expected[r.schema]
+		switch {
+		case r.err != nil:
+			failures = append(failures, fmt.Sprintf("%s stream %d failed after %d records: %s", r.schema, r.stream, r.count, r.err))
+		case r.count != want.count:
+			failures = append(failures, fmt.Sprintf("%s stream %d got %d records, wanted %d", r.schema, r.stream, r.count, want.count))
+		case r.digest != want.digest:
+			failures = append(failures, fmt.Sprintf("%s stream %d got different records than publishing %s alone", r.schema, r.stream, r.schema))
+		}
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.comment("stream latency over %d streams: p50 %s, p90 %s, p99 %s, max %s",
+		len(latencies),
+		percentile(latencies, 50),
+		percentile(latencies, 90),
+		percentile(latencies, 99),
+		latencies[len(latencies)-1])
+
+	if len(failures) > 0 {
+		for _, f := range failures {
+			result.comment("%s", color.RedString(f))
+		}
+		return result.withErr(errors.Errorf("%d of %d concurrent streams were wrong", len(failures), total))
+	}
+
+	result.comment("%s", color.GreenString("all %d concurrent streams got the correct records", total))
+	return result
+}
+
+// publishAndDigest publishes schema and returns a digest of the records received,
+// which doesn't depend on the order they arrived in.
+func publishAndDigest(client plugin.PluginClient, settings *plugin.Settings, schema *plugin.Schema, stream int) *streamResult {
+	r := &streamResult{
+		schema: schema.Name,
+		stream: stream,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() {
+		r.latency = time.Since(start)
+	}()
+
+	publish, err := client.Publish(ctx, &plugin.PublishRequest{
+		Settings: settings,
+		Schema:   schema,
+	})
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	var records []string
+	for {
+		record, err := publish.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.err = err
+			return r
+		}
+		if record.Progress != nil {
+			continue
+		}
+		r.count++
+
+		var data []interface{}
+		if err := json.Unmarshal([]byte(record.Data), &data); err != nil {
+			r.err = errors.Errorf("record %d has corrupt data %q: %s", r.count, record.Data, err)
+			return r
+		}
+		if len(data) != len(schema.Properties) {
+			r.err = errors.Errorf("record %d has %d values but the schema has %d properties: %s", r.count, len(data), len(schema.Properties), record.Data)
+			return r
+		}
+		records = append(records, fmt.Sprintf("%t|%s", record.Invalid, record.Data))
+	}
+
+	sort.Strings(records)
+	sum := sha256.Sum256([]byte(strings.Join(records, "\n")))
+	r.digest = fmt.Sprintf("%x", sum)
+	return r
+}
+
+// percentile returns the pth percentile of sorted, using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
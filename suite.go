@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// suiteGlob matches suite files with tests to run, along with the standard tests.
+var suiteGlob string
+
+func init() {
+	flag.StringVar(&suiteGlob, "suite", "", "glob matching suite files with more tests to run")
+}
+
+// suite is a file of tests, so tests can be written without changing the host.
+type suite struct {
+	Tests []*suiteTest `json:"tests"`
+}
+
+type suiteTest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Glob matches the test's files. Relative globs are relative to the suite file.
+	Glob string `json:"glob"`
+	// Schemas are the schemas the plugin should discover from the files.
+	Schemas []*plugin.Schema `json:"schemas"`
+	// Publish is the name of the schema to publish. The default is the first schema.
+	Publish string `json:"publish"`
+	// Count is the number of records which should be published.
+	Count int `json:"count"`
+	// Assertions are checks on the published records; see assertion.
+	Assertions []string `json:"assertions"`
+}
+
+// testCase returns the test described by s, from a suite in dir.
+func (s *suiteTest) testCase(dir string) (*standardTestCase, error) {
+	if s.Name == "" {
+		return nil, errors.New("a test has no name")
+	}
+	if s.Glob == "" || len(s.Schemas) == 0 {
+		return nil, errors.Errorf("test %s needs a glob and schemas", s.Name)
+	}
+
+	t := &standardTestCase{
+		n:             s.Name,
+		d:             s.Description,
+		glob:          s.Glob,
+		expectedCount: s.Count,
+	}
+	if !filepath.IsAbs(t.glob) {
+		t.glob = filepath.Join(dir, t.glob)
+	}
+	for _, schema := range s.Schemas {
+		t.expectedSchemas = append(t.expectedSchemas, *schema)
+	}
+	t.publishSchema = t.expectedSchemas[0]
+	if s.Publish != "" {
+		found := false
+		for _, schema := range t.expectedSchemas {
+			if schema.Name == s.Publish {
+				t.publishSchema, found = schema, true
+			}
+		}
+		if !found {
+			return nil, errors.Errorf("test %s publishes schema %s, which isn't one of its schemas", s.Name, s.Publish)
+		}
+	}
+
+	for _, text := range s.Assertions {
+		a, err := parseAssertion(text, t.publishSchema)
+		if err != nil {
+			return nil, errors.WithMessage(err, "bad assertion in test "+s.Name+": "+text)
+		}
+		t.assertions = append(t.assertions, a)
+	}
+	return t, nil
+}
+
+// suiteTests returns the tests in the suite files matching -suite.
+func suiteTests() ([]test, error) {
+	if suiteGlob == "" {
+		return nil, nil
+	}
+	paths, err := filepath.Glob(suiteGlob)
+	if err != nil {
+		return nil, errors.Wrap(err, "bad -suite")
+	}
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no suite files match -suite %q", suiteGlob)
+	}
+
+	var tests []test
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		s := new(suite)
+		if err = json.Unmarshal(b, s); err != nil {
+			return nil, errors.Wrapf(err, "bad suite in %s", path)
+		}
+		dir, err := filepath.Abs(filepath.Dir(path))
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range s.Tests {
+			t, err := st.testCase(dir)
+			if err != nil {
+				return nil, errors.WithMessage(err, "bad suite in "+path)
+			}
+			tests = append(tests, t)
+		}
+	}
+	return tests, nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/naveego/code-challenge-plugin/plugin"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// supervise restarts the plugin when it crashes, so the remaining tests can still run.
+var supervise bool
+
+// crashOutputLines is the number of lines of plugin output reported when it crashes.
+const crashOutputLines = 20
+
+// crashDetectDelay is how long to wait for the plugin to exit after a test fails,
+// since the test usually sees the broken connection before the host sees the exit.
+const crashDetectDelay = 100 * time.Millisecond
+
+func init() {
+	flag.BoolVar(&supervise, "supervise", supervise, "restart the plugin if it crashes during a test, and carry on with the remaining tests")
+}
+
+// supervisor keeps track of the plugin the tests are running against,
+// and replaces it when it crashes.
+type supervisor struct {
+	mu     sync.Mutex
+	plugin *pluginProcess
+	client plugin.PluginClient
+}
+
+func (s *supervisor) current() (*pluginProcess, plugin.PluginClient) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.plugin, s.client
+}
+
+// restart replaces old with a new plugin, unless another test has already replaced it.
+func (s *supervisor) restart(old *pluginProcess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.plugin != old {
+		return nil
+	}
+
+	if orphans := old.killOrphans(nil); len(orphans) > 0 {
+		log.Printf("%s", color.RedString("plugin left %d processes running after it exited, killed them: %s", len(orphans), strings.Join(orphans, ", ")))
+	}
+
+	log.Printf("restarting plugin...")
+	p, err := startPlugin(pluginArgs)
+	if err != nil {
+		return err
+	}
+	client, err := connect(p.port)
+	if err != nil {
+		p.expectExit()
+		p.kill()
+		return err
+	}
+	s.plugin, s.client = p, client
+	activePlugin = p
+	log.Printf("plugin restarted (pid %d, port %d)", p.pid(), p.port)
+	return nil
+}
+
+// expectExit marks the plugin as being stopped by the host, so its exit isn't a crash.
+func (p *pluginProcess) expectExit() {
+	atomic.StoreInt32(&p.expected, 1)
+}
+
+func (p *pluginProcess) hasExited() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// crashed reports whether the plugin exited without the host stopping it,
+// waiting up to wait for it to exit.
+func (p *pluginProcess) crashed(wait time.Duration) bool {
+	select {
+	case <-p.exited:
+	case <-time.After(wait):
+		if !p.hasExited() {
+			return false
+		}
+	}
+	return atomic.LoadInt32(&p.expected) == 0
+}
+
+// recordCrash records against result that the plugin crashed, with its exit
+// code and what it wrote after the first lines lines of output.
+func recordCrash(result *testResult, p *pluginProcess, lines int) {
+	// Give the plugin's last words time to arrive.
+	select {
+	case <-p.outputDone:
+	case <-time.After(crashDetectDelay):
+	}
+
+	crash := fmt.Sprintf("plugin crashed with exit code %d", p.exitCode)
+	if output := pluginOutput.since(lines); len(output) > 0 {
+		if len(output) > crashOutputLines {
+			output = output[len(output)-crashOutputLines:]
+		}
+		result.comment("%s", color.RedString("%s; its last output was:\n    %s", crash, strings.Join(output, "\n    ")))
+	} else {
+		result.comment("%s", color.RedString("%s without writing any output", crash))
+	}
+
+	if result.err == nil {
+		result.err = errors.New(crash)
+	} else {
+		result.err = errors.WithMessage(result.err, crash)
+	}
+}